@@ -0,0 +1,100 @@
+package gostockfish
+
+// mateCentipawns is the score in centipawns given to a mate when comparing scores
+const mateCentipawns int = 100000
+
+// Arbitration configures the adjudication of a match by an independent, usually stronger,
+// arbiter engine instead of the scores of the playing engines. The arbiter searches the
+// position to Depth whenever the playing engines disagree, i.e. their last two scores differ
+// by more than Disagreement centipawns, or the game stalls, i.e. StallPlies plies passed
+// without a capture or pawn move. It declares a win if its score exceeds WinScore centipawns
+// for either side (or it sees a mate), and a draw of a stalled game if its score is within
+// DrawScore. Disagreement or StallPlies of 0 disable the respective trigger.
+type Arbitration struct {
+	Arbiter      ArbiterEngine
+	Depth        int
+	Disagreement int
+	StallPlies   int
+	WinScore     int
+	DrawScore    int
+}
+
+// ArbiterEngine is the interface of the arbiter of an Arbitration. It is implemented by *Engine.
+type ArbiterEngine interface {
+	SetPosition(moves []string) error
+	SetFENPositionWithMoves(fen string, moves []string) error
+	GetScore(depth int) (Score, error)
+}
+
+// arbitrate consults the arbiter of match.Arbitration after a move and sets the result once it
+// decides the game
+func (match *Match) arbitrate(bestMove *BestMove, whiteToMove bool) (bool, error) {
+	rules := match.Arbitration
+	if rules == nil || rules.Arbiter == nil {
+		return false, nil
+	}
+
+	disagree := false
+	if bestMove.Info != nil {
+		score := whiteCentipawns(bestMove.Info.Score, whiteToMove)
+		if match.lastScore != nil && rules.Disagreement > 0 && abs(score-*match.lastScore) > rules.Disagreement {
+			disagree = true
+		}
+		match.lastScore = &score
+	}
+	board, err := match.track()
+	if err != nil {
+		return false, err
+	}
+	stalled := rules.StallPlies > 0 && board.HalfmoveClock >= rules.StallPlies
+	if !disagree && !stalled {
+		return false, nil
+	}
+
+	err = match.setPosition(rules.Arbiter)
+	if err != nil {
+		return false, err
+	}
+	arbiterScore, err := rules.Arbiter.GetScore(rules.Depth)
+	if err != nil {
+		return false, err
+	}
+	score := whiteCentipawns(arbiterScore, board.WhiteToMove)
+
+	switch {
+	case score > rules.WinScore:
+		match.setWinner(true)
+	case score < -rules.WinScore:
+		match.setWinner(false)
+	case stalled && abs(score) <= rules.DrawScore:
+	default:
+		return false, nil
+	}
+	match.Adjudicated = true
+	return true, nil
+}
+
+// whiteCentipawns returns a score of the side to move in centipawns from white's point of
+// view, counting mates as mateCentipawns
+func whiteCentipawns(score Score, whiteToMove bool) int {
+	value := score.Value
+	if score.Eval == "mate" {
+		// mate 0: the side to move is checkmated
+		value = -mateCentipawns
+		if score.Value > 0 {
+			value = mateCentipawns
+		}
+	}
+	if !whiteToMove {
+		value = -value
+	}
+	return value
+}
+
+// abs returns the absolute value of x
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
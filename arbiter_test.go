@@ -0,0 +1,89 @@
+package gostockfish
+
+import (
+	"testing"
+)
+
+func TestArbitration(t *testing.T) {
+	info := func(score string, move string) []string {
+		return []string{
+			"info depth 10 seldepth 12 multipv 1 score " + score + " nodes 500 nps 50000 tbhits 0 time 10 pv " + move,
+			"bestmove " + move,
+		}
+	}
+	var tests = []struct {
+		name    string
+		fen     string
+		replies map[int][]string
+		arbiter string
+		winner  string
+		moves   int
+	}{
+		{
+			"engines disagree",
+			StartFEN,
+			map[int][]string{
+				0: info("cp 50", "e2e4"),
+				1: info("cp 300", "e7e5"),
+			},
+			"cp 800",
+			"white",
+			2,
+		},
+		{
+			"engines disagree, arbiter undecided",
+			StartFEN,
+			map[int][]string{
+				0: info("cp 50", "e2e4"),
+				1: info("cp 300", "e7e5"),
+				2: info("mate -3", "g1f3"),
+			},
+			"cp 100",
+			"black",
+			3,
+		},
+		{
+			"stalled",
+			"4k3/8/8/8/8/8/8/R3K3 w - - 40 60",
+			map[int][]string{
+				0: info("cp 20", "a1a2"),
+			},
+			"cp 5",
+			"",
+			1,
+		},
+	}
+	for _, tt := range tests {
+		engine := newPlyEngine(tt.replies)
+		arbiter, _ := newFakeEngine(func(command string) []string {
+			if command == "go depth 20" {
+				return info(tt.arbiter, "a2a3")
+			}
+			return nil
+		})
+		m, err := NewMatchFromFEN("e1", engine, "e2", engine, tt.fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		m.White, m.Black = "white", "black"
+		m.Arbitration = &Arbitration{
+			Arbiter:      arbiter,
+			Depth:        20,
+			Disagreement: 200,
+			StallPlies:   40,
+			WinScore:     500,
+			DrawScore:    20,
+		}
+
+		winner, err := m.Run()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if winner != tt.winner {
+			t.Errorf("%s: expected winner \"%s\", actual \"%s\"", tt.name, tt.winner, winner)
+		}
+		if len(m.Moves) != tt.moves {
+			t.Errorf("%s: expected %d moves, actual %v", tt.name, tt.moves, m.Moves)
+		}
+	}
+}
@@ -0,0 +1,816 @@
+package gostockfish
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// StartFEN is the FEN of the standard starting position
+const StartFEN string = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// Color is the color of a side
+type Color string
+
+// The two colors
+const (
+	ColorWhite Color = "white"
+	ColorBlack Color = "black"
+)
+
+// SANRegex describes the regular expression for SAN moves other than castling
+var SANRegex = regexp.MustCompile(`^(?P<piece>[NBRQK])?(?P<file>[a-h])?(?P<rank>[1-8])?(?P<capture>x)?(?P<to>[a-h][1-8])(=?(?P<promotion>[NBRQ]))?$`)
+
+// Board is a chess position. Squares are indexed from a1 (0) to h8 (63), pieces use the
+// FEN letters (uppercase for white, lowercase for black) and empty squares are 0.
+type Board struct {
+	Squares        [64]byte
+	WhiteToMove    bool
+	Castling       string
+	EnPassant      string
+	HalfmoveClock  int
+	FullmoveNumber int
+}
+
+// move is a move in board coordinates
+type move struct {
+	from      int
+	to        int
+	promotion byte
+}
+
+// UCI returns the move in full algebraic notation (i.e. 'e2e4', 'e7e8q')
+func (m move) UCI() string {
+	uci := squareName(m.from) + squareName(m.to)
+	if m.promotion != 0 {
+		uci += string(toLower(m.promotion))
+	}
+	return uci
+}
+
+var knightOffsets = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+var kingOffsets = [][2]int{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+var bishopDirections = [][2]int{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+var rookDirections = [][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// NewBoard returns the standard starting position
+func NewBoard() *Board {
+	board, _ := ParseFEN(StartFEN)
+	return board
+}
+
+// ParseFEN parses a position in FEN notation, i.e. "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1".
+// The halfmove clock and fullmove number may be omitted (as in EPD) and default to 0 and 1.
+func ParseFEN(fen string) (*Board, error) {
+	fields := strings.Fields(fen)
+	if len(fields) != 4 && len(fields) != 6 {
+		return nil, fmt.Errorf("Could not parse FEN, expected 4 or 6 fields: %s", fen)
+	}
+
+	board := &Board{
+		HalfmoveClock:  0,
+		FullmoveNumber: 1,
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return nil, fmt.Errorf("Could not parse FEN, expected 8 ranks: %s", fen)
+	}
+	for i, rank := range ranks {
+		file := 0
+		for _, c := range rank {
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				continue
+			}
+			if !strings.ContainsRune("PNBRQKpnbrqk", c) {
+				return nil, fmt.Errorf("Could not parse FEN, invalid piece '%c': %s", c, fen)
+			}
+			if file > 7 {
+				return nil, fmt.Errorf("Could not parse FEN, rank %d does not have 8 squares: %s", 8-i, fen)
+			}
+			board.Squares[(7-i)*8+file] = byte(c)
+			file++
+		}
+		if file != 8 {
+			return nil, fmt.Errorf("Could not parse FEN, rank %d does not have 8 squares: %s", 8-i, fen)
+		}
+	}
+
+	if fields[1] == "w" {
+		board.WhiteToMove = true
+	} else if fields[1] != "b" {
+		return nil, fmt.Errorf("Could not parse FEN, invalid side to move '%s': %s", fields[1], fen)
+	}
+
+	if fields[2] != "-" && strings.Trim(fields[2], "KQkq") != "" {
+		return nil, fmt.Errorf("Could not parse FEN, invalid castling rights '%s': %s", fields[2], fen)
+	}
+	board.Castling = fields[2]
+
+	if fields[3] != "-" {
+		if len(fields[3]) != 2 || fields[3][0] < 'a' || fields[3][0] > 'h' || (fields[3][1] != '3' && fields[3][1] != '6') {
+			return nil, fmt.Errorf("Could not parse FEN, invalid en passant square '%s': %s", fields[3], fen)
+		}
+	}
+	board.EnPassant = fields[3]
+
+	if len(fields) == 6 {
+		var err error
+		board.HalfmoveClock, err = strconv.Atoi(fields[4])
+		if err != nil || board.HalfmoveClock < 0 {
+			return nil, fmt.Errorf("Could not parse FEN, invalid halfmove clock '%s': %s", fields[4], fen)
+		}
+		board.FullmoveNumber, err = strconv.Atoi(fields[5])
+		if err != nil || board.FullmoveNumber < 1 {
+			return nil, fmt.Errorf("Could not parse FEN, invalid fullmove number '%s': %s", fields[5], fen)
+		}
+	}
+
+	err := board.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err.Error(), fen)
+	}
+
+	return board, nil
+}
+
+// validate checks that the position could be handed to an engine
+func (board *Board) validate() error {
+	whiteKings, blackKings := 0, 0
+	for sq, piece := range board.Squares {
+		if piece == 'K' {
+			whiteKings++
+		} else if piece == 'k' {
+			blackKings++
+		} else if (piece == 'P' || piece == 'p') && (sq < 8 || sq >= 56) {
+			return errors.New("Invalid position, pawn on first or last rank")
+		}
+	}
+	if whiteKings != 1 || blackKings != 1 {
+		return errors.New("Invalid position, expected exactly one king per side")
+	}
+	if board.attacked(board.kingSquare(!board.WhiteToMove), board.WhiteToMove) {
+		return errors.New("Invalid position, side not to move is in check")
+	}
+	return nil
+}
+
+// FEN returns the position in FEN notation
+func (board *Board) FEN() string {
+	var fen strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			piece := board.Squares[rank*8+file]
+			if piece == 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fen.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			fen.WriteByte(piece)
+		}
+		if empty > 0 {
+			fen.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 0 {
+			fen.WriteByte('/')
+		}
+	}
+
+	side := "b"
+	if board.WhiteToMove {
+		side = "w"
+	}
+	castling := board.Castling
+	if castling == "" {
+		castling = "-"
+	}
+	enPassant := board.EnPassant
+	if enPassant == "" {
+		enPassant = "-"
+	}
+
+	return fmt.Sprintf("%s %s %s %s %d %d", fen.String(), side, castling, enPassant, board.HalfmoveClock, board.FullmoveNumber)
+}
+
+// Key returns a key of the position for detecting repetitions: the first four FEN fields
+// (placement, side to move, castling rights and en passant square). The halfmove clock and
+// fullmove number are left out on purpose, as they differ between repetitions of a position.
+// The en passant square is only included if an en passant capture is legal.
+func (board *Board) Key() string {
+	fields := strings.Fields(board.FEN())
+	if board.EnPassant != "" {
+		ep, _ := parseSquare(board.EnPassant)
+		fields[3] = "-"
+		for _, m := range board.legalMoves() {
+			if m.to == ep && toUpper(board.Squares[m.from]) == 'P' {
+				fields[3] = board.EnPassant
+				break
+			}
+		}
+	}
+	return strings.Join(fields[:4], " ")
+}
+
+// SideToMove returns the color of the side to move
+func (board *Board) SideToMove() Color {
+	if board.WhiteToMove {
+		return ColorWhite
+	}
+	return ColorBlack
+}
+
+// InCheck reports whether the side to move is in check
+func (board *Board) InCheck() bool {
+	return board.attacked(board.kingSquare(board.WhiteToMove), !board.WhiteToMove)
+}
+
+// LegalMoves returns all legal moves of the side to move in full algebraic notation
+func (board *Board) LegalMoves() []string {
+	var moves []string
+	for _, m := range board.legalMoves() {
+		moves = append(moves, m.UCI())
+	}
+	return moves
+}
+
+// Apply plays a move given in full algebraic notation (i.e. 'e2e4'). Returns an error if
+// the move is not legal in the position.
+func (board *Board) Apply(uciMove string) error {
+	err := board.checkPromotion(uciMove)
+	if err != nil {
+		return err
+	}
+	for _, m := range board.legalMoves() {
+		if m.UCI() == uciMove {
+			board.play(m)
+			return nil
+		}
+	}
+	return fmt.Errorf("Illegal move %s in position %s", uciMove, board.FEN())
+}
+
+// checkPromotion requires a promotion piece for pawn moves to the last rank in full algebraic
+// notation and rejects it for all other moves
+func (board *Board) checkPromotion(uciMove string) error {
+	if len(uciMove) != 4 && len(uciMove) != 5 {
+		return fmt.Errorf("Could not parse move %s", uciMove)
+	}
+	from, err := parseSquare(uciMove[:2])
+	if err != nil {
+		return err
+	}
+	to, err := parseSquare(uciMove[2:4])
+	if err != nil {
+		return err
+	}
+	promotes := toUpper(board.Squares[from]) == 'P' && (to/8 == 0 || to/8 == 7)
+	if promotes && len(uciMove) == 4 {
+		return fmt.Errorf("Missing promotion piece in move %s", uciMove)
+	}
+	if len(uciMove) == 5 {
+		if !promotes {
+			return fmt.Errorf("Unexpected promotion piece in move %s", uciMove)
+		}
+		if !strings.ContainsRune("qrbn", rune(uciMove[4])) {
+			return fmt.Errorf("Invalid promotion piece in move %s", uciMove)
+		}
+	}
+	return nil
+}
+
+// ApplyMove plays a move given in full algebraic notation (i.e. 'e2e4') in the position given
+// in FEN notation and returns the new position in FEN notation, see Board.Apply. Returns an
+// error if the move is not legal in the position.
+func ApplyMove(fen string, uciMove string) (string, error) {
+	board, err := ParseFEN(fen)
+	if err != nil {
+		return "", err
+	}
+	err = board.Apply(uciMove)
+	if err != nil {
+		return "", err
+	}
+	return board.FEN(), nil
+}
+
+// PVPositions plays the moves of a principal variation (i.e. Info.Pv) from the position given in
+// FEN notation and returns the FEN after each move. Stops with an error at the first illegal move.
+func PVPositions(fen string, pv string) ([]string, error) {
+	board, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	var positions []string
+	for i, m := range strings.Fields(pv) {
+		err = board.Apply(m)
+		if err != nil {
+			return nil, fmt.Errorf("Could not play move %d of pv: %s", i+1, err.Error())
+		}
+		positions = append(positions, board.FEN())
+	}
+	return positions, nil
+}
+
+// PVFEN returns the FEN of the position the engine foresees at the end of a principal variation
+func PVFEN(fen string, pv string) (string, error) {
+	positions, err := PVPositions(fen, pv)
+	if err != nil {
+		return "", err
+	}
+	if len(positions) == 0 {
+		return fen, nil
+	}
+	return positions[len(positions)-1], nil
+}
+
+// SANToUCI converts a move in standard algebraic notation (i.e. 'Nf3', 'exd5', 'O-O') to
+// full algebraic notation (i.e. 'g1f3', 'e4d5', 'e1g1') for the current position
+func (board *Board) SANToUCI(san string) (string, error) {
+	m, err := board.parseSAN(san)
+	if err != nil {
+		return "", err
+	}
+	return m.UCI(), nil
+}
+
+// UCIToSAN converts a move in full algebraic notation (i.e. 'g1f3') to standard algebraic
+// notation (i.e. 'Nf3') for the current position, including check and mate suffixes
+func (board *Board) UCIToSAN(uciMove string) (string, error) {
+	err := board.checkPromotion(uciMove)
+	if err != nil {
+		return "", err
+	}
+	legal := board.legalMoves()
+	for _, m := range legal {
+		if m.UCI() == uciMove {
+			return board.san(m, legal), nil
+		}
+	}
+	return "", fmt.Errorf("Illegal move %s in position %s", uciMove, board.FEN())
+}
+
+// san returns the legal move m in standard algebraic notation
+func (board *Board) san(m move, legal []move) string {
+	piece := toUpper(board.Squares[m.from])
+	var san strings.Builder
+
+	switch {
+	case piece == 'K' && m.to-m.from == 2:
+		san.WriteString("O-O")
+	case piece == 'K' && m.from-m.to == 2:
+		san.WriteString("O-O-O")
+	case piece == 'P':
+		if m.from%8 != m.to%8 {
+			san.WriteByte(squareName(m.from)[0])
+			san.WriteByte('x')
+		}
+		san.WriteString(squareName(m.to))
+		if m.promotion != 0 {
+			san.WriteByte('=')
+			san.WriteByte(toUpper(m.promotion))
+		}
+	default:
+		san.WriteByte(piece)
+		// disambiguate from other pieces of the same kind moving to the same square
+		ambiguous, sameFile, sameRank := false, false, false
+		for _, other := range legal {
+			if other.from == m.from || other.to != m.to || toUpper(board.Squares[other.from]) != piece {
+				continue
+			}
+			ambiguous = true
+			sameFile = sameFile || other.from%8 == m.from%8
+			sameRank = sameRank || other.from/8 == m.from/8
+		}
+		if ambiguous && (!sameFile || sameRank) {
+			san.WriteByte(squareName(m.from)[0])
+		}
+		if ambiguous && sameFile {
+			san.WriteByte(squareName(m.from)[1])
+		}
+		if board.Squares[m.to] != 0 {
+			san.WriteByte('x')
+		}
+		san.WriteString(squareName(m.to))
+	}
+
+	next := *board
+	next.play(m)
+	if next.InCheck() {
+		if len(next.legalMoves()) == 0 {
+			san.WriteByte('#')
+		} else {
+			san.WriteByte('+')
+		}
+	}
+	return san.String()
+}
+
+// parseSAN resolves a move in standard algebraic notation against the legal moves
+func (board *Board) parseSAN(san string) (move, error) {
+	clean := strings.TrimRight(san, "+#!?")
+	legal := board.legalMoves()
+
+	if clean == "O-O" || clean == "0-0" || clean == "O-O-O" || clean == "0-0-0" {
+		from := board.kingSquare(board.WhiteToMove)
+		to := from + 2
+		if len(clean) == 5 {
+			to = from - 2
+		}
+		for _, m := range legal {
+			if m.from == from && m.to == to && toUpper(board.Squares[from]) == 'K' {
+				return m, nil
+			}
+		}
+		return move{}, fmt.Errorf("Illegal move %s", san)
+	}
+
+	matches := SANRegex.FindStringSubmatch(clean)
+	if matches == nil {
+		return move{}, fmt.Errorf("Could not parse SAN move %s", san)
+	}
+	piece := byte('P')
+	if matches[1] != "" {
+		piece = matches[1][0]
+	}
+	fromFile, fromRank := -1, -1
+	if matches[2] != "" {
+		fromFile = int(matches[2][0] - 'a')
+	}
+	if matches[3] != "" {
+		fromRank = int(matches[3][0] - '1')
+	}
+	to, _ := parseSquare(matches[5])
+	var promotion byte
+	if matches[7] != "" {
+		promotion = matches[7][0]
+	}
+	if piece == 'P' && (to/8 == 0 || to/8 == 7) && promotion == 0 {
+		return move{}, fmt.Errorf("Missing promotion piece in move %s", san)
+	}
+	if promotion != 0 && (piece != 'P' || (to/8 != 0 && to/8 != 7)) {
+		return move{}, fmt.Errorf("Unexpected promotion piece in move %s", san)
+	}
+
+	var candidates []move
+	for _, m := range legal {
+		if toUpper(board.Squares[m.from]) != piece || m.to != to {
+			continue
+		}
+		if (fromFile >= 0 && m.from%8 != fromFile) || (fromRank >= 0 && m.from/8 != fromRank) {
+			continue
+		}
+		if toUpper(m.promotion) != promotion {
+			continue
+		}
+		candidates = append(candidates, m)
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	} else if len(candidates) > 1 {
+		return move{}, fmt.Errorf("Ambiguous move %s", san)
+	}
+
+	for sq, p := range board.Squares {
+		if p == 0 || isWhite(p) != board.WhiteToMove || toUpper(p) != piece {
+			continue
+		}
+		if (fromFile < 0 || sq%8 == fromFile) && (fromRank < 0 || sq/8 == fromRank) {
+			return move{}, fmt.Errorf("Illegal move %s", san)
+		}
+	}
+	return move{}, fmt.Errorf("No such piece for move %s", san)
+}
+
+// legalMoves returns the pseudo-legal moves that do not leave the own king in check
+func (board *Board) legalMoves() []move {
+	var legal []move
+	for _, m := range board.pseudoLegalMoves() {
+		next := *board
+		next.play(m)
+		if !next.attacked(next.kingSquare(board.WhiteToMove), next.WhiteToMove) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// pseudoLegalMoves returns the moves of the side to move without checking whether the own king is left in check
+func (board *Board) pseudoLegalMoves() []move {
+	var moves []move
+	white := board.WhiteToMove
+
+	for from, piece := range board.Squares {
+		if piece == 0 || isWhite(piece) != white {
+			continue
+		}
+		file, rank := from%8, from/8
+
+		switch toUpper(piece) {
+		case 'P':
+			forward, startRank, lastRank := 1, 1, 7
+			if !white {
+				forward, startRank, lastRank = -1, 6, 0
+			}
+			addPawnMove := func(to int) {
+				if to/8 == lastRank {
+					for _, promotion := range []byte("QRBN") {
+						if !white {
+							promotion = toLower(promotion)
+						}
+						moves = append(moves, move{from, to, promotion})
+					}
+				} else {
+					moves = append(moves, move{from: from, to: to})
+				}
+			}
+			one := from + 8*forward
+			if board.Squares[one] == 0 {
+				addPawnMove(one)
+				two := one + 8*forward
+				if rank == startRank && board.Squares[two] == 0 {
+					moves = append(moves, move{from: from, to: two})
+				}
+			}
+			for _, df := range []int{-1, 1} {
+				if file+df < 0 || file+df > 7 {
+					continue
+				}
+				to := one + df
+				target := board.Squares[to]
+				if (target != 0 && isWhite(target) != white) || (target == 0 && squareName(to) == board.EnPassant) {
+					addPawnMove(to)
+				}
+			}
+		case 'N':
+			moves = board.appendSteps(moves, from, knightOffsets)
+		case 'B':
+			moves = board.appendSlides(moves, from, bishopDirections)
+		case 'R':
+			moves = board.appendSlides(moves, from, rookDirections)
+		case 'Q':
+			moves = board.appendSlides(moves, from, bishopDirections)
+			moves = board.appendSlides(moves, from, rookDirections)
+		case 'K':
+			moves = board.appendSteps(moves, from, kingOffsets)
+			moves = board.appendCastling(moves, from)
+		}
+	}
+
+	return moves
+}
+
+// appendSteps adds single step moves (knight, king) to empty or enemy squares
+func (board *Board) appendSteps(moves []move, from int, offsets [][2]int) []move {
+	white := isWhite(board.Squares[from])
+	for _, offset := range offsets {
+		file, rank := from%8+offset[0], from/8+offset[1]
+		if file < 0 || file > 7 || rank < 0 || rank > 7 {
+			continue
+		}
+		to := rank*8 + file
+		if board.Squares[to] == 0 || isWhite(board.Squares[to]) != white {
+			moves = append(moves, move{from: from, to: to})
+		}
+	}
+	return moves
+}
+
+// appendSlides adds sliding moves (bishop, rook, queen) up to the first blocking piece
+func (board *Board) appendSlides(moves []move, from int, directions [][2]int) []move {
+	white := isWhite(board.Squares[from])
+	for _, direction := range directions {
+		file, rank := from%8, from/8
+		for {
+			file, rank = file+direction[0], rank+direction[1]
+			if file < 0 || file > 7 || rank < 0 || rank > 7 {
+				break
+			}
+			to := rank*8 + file
+			if board.Squares[to] == 0 {
+				moves = append(moves, move{from: from, to: to})
+				continue
+			}
+			if isWhite(board.Squares[to]) != white {
+				moves = append(moves, move{from: from, to: to})
+			}
+			break
+		}
+	}
+	return moves
+}
+
+// appendCastling adds castling moves if the rights are available, the squares between king
+// and rook are empty and the king does not pass through or start in check
+func (board *Board) appendCastling(moves []move, from int) []move {
+	white := board.WhiteToMove
+	home, kingSide, queenSide, rook := 4, "K", "Q", byte('R')
+	if !white {
+		home, kingSide, queenSide, rook = 60, "k", "q", 'r'
+	}
+	if from != home || board.attacked(home, !white) {
+		return moves
+	}
+	squares := board.Squares
+	if strings.Contains(board.Castling, kingSide) && squares[home+3] == rook &&
+		squares[home+1] == 0 && squares[home+2] == 0 &&
+		!board.attacked(home+1, !white) && !board.attacked(home+2, !white) {
+		moves = append(moves, move{from: home, to: home + 2})
+	}
+	if strings.Contains(board.Castling, queenSide) && squares[home-4] == rook &&
+		squares[home-1] == 0 && squares[home-2] == 0 && squares[home-3] == 0 &&
+		!board.attacked(home-1, !white) && !board.attacked(home-2, !white) {
+		moves = append(moves, move{from: home, to: home - 2})
+	}
+	return moves
+}
+
+// attacked reports whether a square is attacked by the given side
+func (board *Board) attacked(sq int, byWhite bool) bool {
+	file, rank := sq%8, sq/8
+	own := func(piece byte, kind byte) bool {
+		return piece != 0 && isWhite(piece) == byWhite && toUpper(piece) == kind
+	}
+	at := func(f, r int) byte {
+		if f < 0 || f > 7 || r < 0 || r > 7 {
+			return 0
+		}
+		return board.Squares[r*8+f]
+	}
+
+	pawnRank := rank - 1
+	if !byWhite {
+		pawnRank = rank + 1
+	}
+	if own(at(file-1, pawnRank), 'P') || own(at(file+1, pawnRank), 'P') {
+		return true
+	}
+	for _, offset := range knightOffsets {
+		if own(at(file+offset[0], rank+offset[1]), 'N') {
+			return true
+		}
+	}
+	for _, offset := range kingOffsets {
+		if own(at(file+offset[0], rank+offset[1]), 'K') {
+			return true
+		}
+	}
+	slides := func(directions [][2]int, kind byte) bool {
+		for _, direction := range directions {
+			f, r := file, rank
+			for {
+				f, r = f+direction[0], r+direction[1]
+				if f < 0 || f > 7 || r < 0 || r > 7 {
+					break
+				}
+				piece := board.Squares[r*8+f]
+				if piece == 0 {
+					continue
+				}
+				if own(piece, kind) || own(piece, 'Q') {
+					return true
+				}
+				break
+			}
+		}
+		return false
+	}
+	return slides(bishopDirections, 'B') || slides(rookDirections, 'R')
+}
+
+// kingSquare returns the square of the king of the given side, or -1 if there is none
+func (board *Board) kingSquare(white bool) int {
+	king := byte('K')
+	if !white {
+		king = 'k'
+	}
+	for sq, piece := range board.Squares {
+		if piece == king {
+			return sq
+		}
+	}
+	return -1
+}
+
+// play makes a pseudo-legal move and updates castling rights, en passant square and move counters
+func (board *Board) play(m move) {
+	piece := board.Squares[m.from]
+	captured := board.Squares[m.to]
+	white := isWhite(piece)
+	kind := toUpper(piece)
+
+	board.Squares[m.to] = piece
+	board.Squares[m.from] = 0
+
+	if kind == 'P' && m.from%8 != m.to%8 && captured == 0 {
+		// en passant, the captured pawn is behind the target square
+		if white {
+			board.Squares[m.to-8] = 0
+		} else {
+			board.Squares[m.to+8] = 0
+		}
+	}
+	if kind == 'K' && m.to-m.from == 2 {
+		board.Squares[m.from+1] = board.Squares[m.from+3]
+		board.Squares[m.from+3] = 0
+	} else if kind == 'K' && m.from-m.to == 2 {
+		board.Squares[m.from-1] = board.Squares[m.from-4]
+		board.Squares[m.from-4] = 0
+	}
+	if m.promotion != 0 {
+		board.Squares[m.to] = m.promotion
+	}
+
+	for _, sq := range []int{m.from, m.to} {
+		switch sq {
+		case 0:
+			board.removeCastling("Q")
+		case 7:
+			board.removeCastling("K")
+		case 56:
+			board.removeCastling("q")
+		case 63:
+			board.removeCastling("k")
+		}
+	}
+	if kind == 'K' && white {
+		board.removeCastling("KQ")
+	} else if kind == 'K' {
+		board.removeCastling("kq")
+	}
+
+	// the en passant square is only recorded if a pawn could actually capture
+	board.EnPassant = "-"
+	if kind == 'P' && (m.to-m.from == 16 || m.from-m.to == 16) {
+		enemyPawn := byte('p')
+		if !white {
+			enemyPawn = 'P'
+		}
+		file := m.to % 8
+		if (file > 0 && board.Squares[m.to-1] == enemyPawn) || (file < 7 && board.Squares[m.to+1] == enemyPawn) {
+			board.EnPassant = squareName((m.from + m.to) / 2)
+		}
+	}
+
+	if kind == 'P' || captured != 0 {
+		board.HalfmoveClock = 0
+	} else {
+		board.HalfmoveClock++
+	}
+	if !white {
+		board.FullmoveNumber++
+	}
+	board.WhiteToMove = !white
+}
+
+// removeCastling removes the given castling rights, i.e. "KQ" when the white king moves
+func (board *Board) removeCastling(rights string) {
+	castling := strings.Map(func(r rune) rune {
+		if r == '-' || strings.ContainsRune(rights, r) {
+			return -1
+		}
+		return r
+	}, board.Castling)
+	if castling == "" {
+		castling = "-"
+	}
+	board.Castling = castling
+}
+
+// squareName returns the name of a square, i.e. 'e4'
+func squareName(sq int) string {
+	return string([]byte{byte('a' + sq%8), byte('1' + sq/8)})
+}
+
+// parseSquare returns the index of a square given by name, i.e. 'e4'
+func parseSquare(name string) (int, error) {
+	if len(name) != 2 || name[0] < 'a' || name[0] > 'h' || name[1] < '1' || name[1] > '8' {
+		return 0, fmt.Errorf("Invalid square %s", name)
+	}
+	return int(name[1]-'1')*8 + int(name[0]-'a'), nil
+}
+
+func isWhite(piece byte) bool {
+	return piece >= 'A' && piece <= 'Z'
+}
+
+func toUpper(piece byte) byte {
+	if piece >= 'a' && piece <= 'z' {
+		return piece - 'a' + 'A'
+	}
+	return piece
+}
+
+func toLower(piece byte) byte {
+	if piece >= 'A' && piece <= 'Z' {
+		return piece - 'A' + 'a'
+	}
+	return piece
+}
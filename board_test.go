@@ -0,0 +1,352 @@
+package gostockfish
+
+import (
+	"reflect"
+	"testing"
+)
+
+// perft counts the leaf nodes of the legal move tree up to the given depth
+func perft(board *Board, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	nodes := 0
+	for _, m := range board.legalMoves() {
+		next := *board
+		next.play(m)
+		nodes += perft(&next, depth-1)
+	}
+	return nodes
+}
+
+func TestPerft(t *testing.T) {
+	var tests = []struct {
+		fen      string
+		depth    int
+		expected int
+	}{
+		{StartFEN, 3, 8902},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", 2, 2039},
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 4, 43238},
+		{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 3, 9467},
+		{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", 3, 62379},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if actual := perft(board, tt.depth); actual != tt.expected {
+			t.Errorf("perft(\"%s\", %d): expected %d, actual %d", tt.fen, tt.depth, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseFEN(t *testing.T) {
+	var tests = []string{
+		StartFEN,
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/8/8/8/8/8/8/K1k5 b - - 12 60",
+	}
+	for _, fen := range tests {
+		board, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if actual := board.FEN(); actual != fen {
+			t.Errorf("ParseFEN(\"%s\").FEN(): actual %s", fen, actual)
+		}
+	}
+
+	var invalid = []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnrp/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBN1R w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQ1BNR w - - 0 1",
+		"4k3/8/8/8/8/8/8/4K2R w KQkq - 0 1 extra",
+	}
+	for _, fen := range invalid {
+		if _, err := ParseFEN(fen); err == nil {
+			t.Errorf("ParseFEN(\"%s\"): expected error", fen)
+		}
+	}
+}
+
+func TestSANToUCI(t *testing.T) {
+	var tests = []struct {
+		fen      string
+		san      string
+		expected string
+	}{
+		{StartFEN, "e4", "e2e4"},
+		{StartFEN, "Nf3", "g1f3"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "O-O-O", "e1c1"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "dxe6", "d5e6"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "Qxh3+", "f3h3"},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", "exf6", "e5f6"},
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "Nbd2", "b1d2"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8=N", "b7b8n"},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		actual, err := board.SANToUCI(tt.san)
+		if err != nil {
+			t.Errorf("SANToUCI(\"%s\"): %s", tt.san, err)
+		} else if actual != tt.expected {
+			t.Errorf("SANToUCI(\"%s\"): expected %s, actual %s", tt.san, tt.expected, actual)
+		}
+	}
+
+	var invalid = []struct {
+		fen string
+		san string
+	}{
+		{StartFEN, "e5"},
+		{StartFEN, "Qd4"},
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "Nd2"},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "Bb5"},
+		{StartFEN, "O-O"},
+	}
+	for _, tt := range invalid {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if _, err := board.SANToUCI(tt.san); err == nil {
+			t.Errorf("SANToUCI(\"%s\") in \"%s\": expected error", tt.san, tt.fen)
+		}
+	}
+}
+
+func TestUCIToSAN(t *testing.T) {
+	var tests = []struct {
+		fen      string
+		uci      string
+		expected string
+	}{
+		{StartFEN, "e2e4", "e4"},
+		{StartFEN, "g1f3", "Nf3"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1c1", "O-O-O"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "d5e6", "dxe6"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "f3f6", "Qxf6"},
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", "e5f6", "exf6"},
+		{"4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1", "b1d2", "Nbd2"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"8/7k/8/8/8/2Q1Q3/8/2Q1K3 w - - 0 1", "c3d2", "Qc3d2"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q+"},
+		{"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", "h5f7", "Qxf7#"},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		actual, err := board.UCIToSAN(tt.uci)
+		if err != nil {
+			t.Errorf("UCIToSAN(\"%s\"): %s", tt.uci, err)
+		} else if actual != tt.expected {
+			t.Errorf("UCIToSAN(\"%s\"): expected %s, actual %s", tt.uci, tt.expected, actual)
+		}
+	}
+
+	board := NewBoard()
+	if _, err := board.UCIToSAN("e2e5"); err == nil {
+		t.Errorf("UCIToSAN(\"e2e5\"): expected error")
+	}
+}
+
+func TestPromotion(t *testing.T) {
+	fen := "3rk3/2P5/8/8/8/8/4p3/4K3 w - - 0 1"
+	var tests = []struct {
+		uci string
+		san string
+	}{
+		{"c7c8q", "c8=Q"},
+		{"c7c8n", "c8=N"},
+		{"c7d8q", "cxd8=Q+"},
+		{"c7d8r", "cxd8=R+"},
+		{"c7d8b", "cxd8=B"},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		san, err := board.UCIToSAN(tt.uci)
+		if err != nil {
+			t.Errorf("UCIToSAN(\"%s\"): %s", tt.uci, err)
+		} else if san != tt.san {
+			t.Errorf("UCIToSAN(\"%s\"): expected %s, actual %s", tt.uci, tt.san, san)
+		}
+		uci, err := board.SANToUCI(tt.san)
+		if err != nil {
+			t.Errorf("SANToUCI(\"%s\"): %s", tt.san, err)
+		} else if uci != tt.uci {
+			t.Errorf("SANToUCI(\"%s\"): expected %s, actual %s", tt.san, tt.uci, uci)
+		}
+		if err := board.Apply(tt.uci); err != nil {
+			t.Errorf("Apply(\"%s\"): %s", tt.uci, err)
+		}
+	}
+
+	var invalid = []string{"c7c8", "c7d8", "e1d1q", "c7c8k", "c7c8p"}
+	for _, uci := range invalid {
+		board, _ := ParseFEN(fen)
+		if err := board.Apply(uci); err == nil {
+			t.Errorf("Apply(\"%s\"): expected error", uci)
+		}
+	}
+	for _, san := range []string{"c8", "cxd8", "Kd1=Q", "Kf2=N"} {
+		board, _ := ParseFEN(fen)
+		if _, err := board.SANToUCI(san); err == nil {
+			t.Errorf("SANToUCI(\"%s\"): expected error", san)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	board := NewBoard()
+	start := board.Key()
+	if start != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -" {
+		t.Errorf("Key(): unexpected key %s", start)
+	}
+	keys := map[string]int{start: 1}
+	for _, m := range []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"} {
+		err := board.Apply(m)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		keys[board.Key()]++
+	}
+	if keys[start] != 3 {
+		t.Errorf("Key(): expected the start position to repeat 3 times, actual %d", keys[start])
+	}
+	if board.FEN() == StartFEN {
+		t.Errorf("FEN(): expected move counters to differ from the start position")
+	}
+
+	// the en passant square only counts if the capture is possible
+	var tests = []struct {
+		fen      string
+		expected string
+	}{
+		{"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3", "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3"},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -"},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if actual := board.Key(); actual != tt.expected {
+			t.Errorf("Key(\"%s\"): expected %s, actual %s", tt.fen, tt.expected, actual)
+		}
+	}
+}
+
+func TestApplyMove(t *testing.T) {
+	var tests = []struct {
+		fen      string
+		move     string
+		expected string
+	}{
+		// the en passant square is only set if an en passant capture is possible, as by stockfish
+		{StartFEN, "e2e4", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"},
+		{"rnbqkbnr/ppp1pppp/8/8/3p4/5N2/PPPPPPPP/RNBQKB1R w KQkq - 0 3", "e2e4", "rnbqkbnr/ppp1pppp/8/8/3pP3/5N2/PPPP1PPP/RNBQKB1R b KQkq e3 0 3"},
+		// knight move increments the halfmove clock, black's move the fullmove number
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", "g1f3", "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2", "b8c6", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"},
+		// en passant capture removes the pawn behind the target square
+		{"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", "e5f6", "rnbqkbnr/ppp1p1pp/5P2/3p4/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 3"},
+		{"rnbqkbnr/ppp1pppp/8/8/3pP3/5N2/PPPP1PPP/RNBQKB1R b KQkq e3 0 3", "d4e3", "rnbqkbnr/ppp1pppp/8/8/8/4pN2/PPPP1PPP/RNBQKB1R w KQkq - 0 4"},
+		// castling moves the rook and loses both rights of the side
+		{"r3k2r/pppq1ppp/2n2n2/2bpp3/2BPP3/2N2N2/PPPQ1PPP/R3K2R w KQkq - 4 8", "e1g1", "r3k2r/pppq1ppp/2n2n2/2bpp3/2BPP3/2N2N2/PPPQ1PPP/R4RK1 b kq - 5 8"},
+		{"r3k2r/pppq1ppp/2n2n2/2bpp3/2BPP3/2N2N2/PPPQ1PPP/R4RK1 b kq - 5 8", "e8c8", "2kr3r/pppq1ppp/2n2n2/2bpp3/2BPP3/2N2N2/PPPQ1PPP/R4RK1 w - - 6 9"},
+		// a king move loses both rights, a rook move one
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1e2", "r3k2r/8/8/8/8/8/4K3/R6R b kq - 1 1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "h8h6", "r3k3/8/7r/8/8/8/8/R3K2R w KQq - 1 2"},
+		// capturing a rook on its square loses the right of the other side
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "a1a8", "R3k2r/8/8/8/8/8/8/4K2R b Kk - 0 1"},
+		// promotion and underpromotion with capture
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8q", "Q3k3/8/8/8/8/8/8/4K3 b - - 0 1"},
+		{"1r2k3/P7/8/8/8/8/8/4K3 w - - 3 40", "a7b8n", "1N2k3/8/8/8/8/8/8/4K3 b - - 0 40"},
+	}
+	for _, tt := range tests {
+		actual, err := ApplyMove(tt.fen, tt.move)
+		if err != nil {
+			t.Errorf("ApplyMove(\"%s\", \"%s\"): %s", tt.fen, tt.move, err)
+			continue
+		}
+		if actual != tt.expected {
+			t.Errorf("ApplyMove(\"%s\", \"%s\"): expected %s, actual %s", tt.fen, tt.move, tt.expected, actual)
+		}
+	}
+
+	for _, illegal := range []struct{ fen, move string }{
+		{StartFEN, "e2e5"},
+		{StartFEN, "e7e5"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w kq - 0 1", "e1g1"},
+		{"4k3/P7/8/8/8/8/8/4K3 w - - 0 1", "a7a8"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0", "e2e4"},
+	} {
+		if _, err := ApplyMove(illegal.fen, illegal.move); err == nil {
+			t.Errorf("ApplyMove(\"%s\", \"%s\"): expected error", illegal.fen, illegal.move)
+		}
+	}
+}
+
+func TestPVPositions(t *testing.T) {
+	positions, err := PVPositions(StartFEN, "e2e4 e7e5 g1f3")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+		"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2",
+	}
+	if !reflect.DeepEqual(positions, expected) {
+		t.Errorf("PVPositions: expected %v, actual %v", expected, positions)
+	}
+
+	fen, err := PVFEN(StartFEN, "e2e4 e7e5 g1f3")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if fen != expected[2] {
+		t.Errorf("PVFEN: expected %s, actual %s", expected[2], fen)
+	}
+
+	_, err = PVPositions(StartFEN, "e2e4 e2e4")
+	if err == nil {
+		t.Errorf("PVPositions: expected error for illegal move")
+	}
+}
+
+func TestBoardInCheck(t *testing.T) {
+	var tests = []struct {
+		fen      string
+		expected bool
+	}{
+		{StartFEN, false},
+		{"r1bqkb1r/pppp1Qpp/2n2n2/4p3/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 0 4", true},
+		{"4k3/8/8/8/8/8/3n4/4K3 w - - 0 1", false},
+		{"4k3/8/8/8/8/5n2/8/4K3 w - - 0 1", true},
+	}
+	for _, tt := range tests {
+		board, err := ParseFEN(tt.fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if actual := board.InCheck(); actual != tt.expected {
+			t.Errorf("InCheck(\"%s\"): expected %v, actual %v", tt.fen, tt.expected, actual)
+		}
+	}
+}
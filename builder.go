@@ -0,0 +1,158 @@
+package gostockfish
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PositionBuilder constructs a position piece by piece, i.e.
+//
+//	fen, err := NewPositionBuilder().Place('K', "e1").Place('R', "h1").Place('k', "e8").Castling("K").FEN()
+//
+// Errors are collected and returned by FEN or Board, which also validate the position.
+type PositionBuilder struct {
+	board Board
+	err   error
+}
+
+// NewPositionBuilder returns a builder for an empty board with white to move
+func NewPositionBuilder() *PositionBuilder {
+	return &PositionBuilder{
+		board: Board{
+			WhiteToMove:    true,
+			FullmoveNumber: 1,
+		},
+	}
+}
+
+// Squares sets all squares at once, indexed from a1 (0) to h8 (63) as in Board
+func (builder *PositionBuilder) Squares(squares [64]byte) *PositionBuilder {
+	builder.board.Squares = squares
+	return builder
+}
+
+// Place puts a piece (a FEN letter, uppercase for white) on a square, i.e. Place('N', "g1")
+func (builder *PositionBuilder) Place(piece byte, square string) *PositionBuilder {
+	if !strings.ContainsRune("PNBRQKpnbrqk", rune(piece)) {
+		builder.fail(fmt.Errorf("Invalid piece '%c'", piece))
+		return builder
+	}
+	sq, err := parseSquare(square)
+	if err != nil {
+		builder.fail(err)
+		return builder
+	}
+	builder.board.Squares[sq] = piece
+	return builder
+}
+
+// Remove empties a square
+func (builder *PositionBuilder) Remove(square string) *PositionBuilder {
+	sq, err := parseSquare(square)
+	if err != nil {
+		builder.fail(err)
+		return builder
+	}
+	builder.board.Squares[sq] = 0
+	return builder
+}
+
+// SideToMove sets the side to move
+func (builder *PositionBuilder) SideToMove(color Color) *PositionBuilder {
+	builder.board.WhiteToMove = color == ColorWhite
+	return builder
+}
+
+// Castling sets the castling rights, i.e. "KQkq" or "-"
+func (builder *PositionBuilder) Castling(rights string) *PositionBuilder {
+	if rights == "-" {
+		rights = ""
+	}
+	if strings.Trim(rights, "KQkq") != "" {
+		builder.fail(fmt.Errorf("Invalid castling rights '%s'", rights))
+		return builder
+	}
+	builder.board.Castling = rights
+	return builder
+}
+
+// EnPassant sets the en passant target square, i.e. "e3" after the double step e2e4
+func (builder *PositionBuilder) EnPassant(square string) *PositionBuilder {
+	if square == "-" {
+		square = ""
+	}
+	if square != "" {
+		if _, err := parseSquare(square); err != nil {
+			builder.fail(err)
+			return builder
+		}
+	}
+	builder.board.EnPassant = square
+	return builder
+}
+
+// Clocks sets the halfmove clock and the fullmove number
+func (builder *PositionBuilder) Clocks(halfmove int, fullmove int) *PositionBuilder {
+	builder.board.HalfmoveClock = halfmove
+	builder.board.FullmoveNumber = fullmove
+	return builder
+}
+
+// FEN returns the position in FEN notation, or the first error of the builder if the
+// position is invalid
+func (builder *PositionBuilder) FEN() (string, error) {
+	if builder.err != nil {
+		return "", builder.err
+	}
+	board := builder.board
+
+	for _, right := range board.Castling {
+		king, rook, kingPiece, rookPiece := 4, 7, byte('K'), byte('R')
+		switch right {
+		case 'Q':
+			rook = 0
+		case 'k':
+			king, rook, kingPiece, rookPiece = 60, 63, 'k', 'r'
+		case 'q':
+			king, rook, kingPiece, rookPiece = 60, 56, 'k', 'r'
+		}
+		if board.Squares[king] != kingPiece || board.Squares[rook] != rookPiece {
+			return "", fmt.Errorf("Invalid castling right '%c', king or rook is not on its initial square", right)
+		}
+	}
+
+	if board.EnPassant != "" {
+		sq, _ := parseSquare(board.EnPassant)
+		// the pawn which just moved two squares has passed sq and stands in front of it
+		rank, pawn, front, origin := 5, byte('p'), sq-8, sq+8
+		if !board.WhiteToMove {
+			rank, pawn, front, origin = 2, 'P', sq+8, sq-8
+		}
+		if sq/8 != rank || board.Squares[sq] != 0 || board.Squares[origin] != 0 || board.Squares[front] != pawn {
+			return "", fmt.Errorf("Invalid en passant square %s, no pawn has just moved two squares", board.EnPassant)
+		}
+	}
+
+	fen := board.FEN()
+	_, err := ParseFEN(fen)
+	if err != nil {
+		return "", err
+	}
+	return fen, nil
+}
+
+// Board returns the validated position
+func (builder *PositionBuilder) Board() (*Board, error) {
+	fen, err := builder.FEN()
+	if err != nil {
+		return nil, err
+	}
+	return ParseFEN(fen)
+}
+
+// fail records the first error
+func (builder *PositionBuilder) fail(err error) {
+	if builder.err == nil {
+		builder.err = err
+	}
+}
@@ -0,0 +1,48 @@
+package gostockfish
+
+import (
+	"testing"
+)
+
+func TestPositionBuilder(t *testing.T) {
+	fen, err := NewPositionBuilder().
+		Place('K', "e1").Place('R', "h1").Place('P', "e4").
+		Place('k', "e8").Place('p', "d4").
+		SideToMove(ColorBlack).Castling("K").EnPassant("e3").Clocks(0, 12).
+		FEN()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := "4k3/8/8/8/3pP3/8/8/4K2R b K e3 0 12"
+	if fen != expected {
+		t.Errorf("PositionBuilder.FEN(): expected %s, actual %s", expected, fen)
+	}
+
+	board := *NewBoard()
+	fen, err = NewPositionBuilder().Squares(board.Squares).Castling("KQkq").FEN()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if fen != StartFEN {
+		t.Errorf("PositionBuilder.FEN() from squares: expected %s, actual %s", StartFEN, fen)
+	}
+
+	var invalid = []struct {
+		name    string
+		builder *PositionBuilder
+	}{
+		{"no kings", NewPositionBuilder()},
+		{"invalid piece", NewPositionBuilder().Place('x', "e1")},
+		{"invalid square", NewPositionBuilder().Place('K', "e9")},
+		{"castling without rook", NewPositionBuilder().Place('K', "e1").Place('k', "e8").Castling("K")},
+		{"castling with moved king", NewPositionBuilder().Place('K', "e2").Place('R', "h1").Place('k', "e8").Castling("K")},
+		{"en passant without pawn", NewPositionBuilder().Place('K', "e1").Place('k', "e8").SideToMove(ColorBlack).EnPassant("e3")},
+		{"en passant on wrong rank", NewPositionBuilder().Place('K', "e1").Place('P', "e4").Place('k', "e8").EnPassant("e3")},
+		{"side not to move in check", NewPositionBuilder().Place('K', "e1").Place('R', "e2").Place('k', "e8")},
+	}
+	for _, tt := range invalid {
+		if fen, err := tt.builder.FEN(); err == nil {
+			t.Errorf("PositionBuilder.FEN() with %s: expected error, actual %s", tt.name, fen)
+		}
+	}
+}
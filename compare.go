@@ -0,0 +1,51 @@
+package gostockfish
+
+import (
+	"context"
+	"fmt"
+)
+
+// Comparison holds the results of two engines searching the same position
+type Comparison struct {
+	FEN   string
+	A     *BestMove
+	B     *BestMove
+	Agree bool
+}
+
+// CompareEvaluations searches the position independently with both engines to the given depth.
+// The engines agree if they play the same best move, or if both see a forced mate for the same
+// side: different mating moves (and mate distances, which depend on the search depth) are
+// equally winning.
+func CompareEvaluations(fen string, depth int, a, b *Engine) (*Comparison, error) {
+	comparison := &Comparison{FEN: fen}
+
+	var err error
+	comparison.A, err = a.searchFEN(fen, depth)
+	if err != nil {
+		return nil, err
+	}
+	comparison.B, err = b.searchFEN(fen, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison.Agree = comparison.A.Move == comparison.B.Move
+	if !comparison.Agree && comparison.A.Info != nil && comparison.B.Info != nil {
+		scoreA, scoreB := comparison.A.Info.Score, comparison.B.Info.Score
+		if scoreA.Eval == "mate" && scoreB.Eval == "mate" {
+			comparison.Agree = (scoreA.Value > 0) == (scoreB.Value > 0)
+		}
+	}
+
+	return comparison, nil
+}
+
+// searchFEN searches the position in FEN notation to the given depth
+func (engine *Engine) searchFEN(fen string, depth int) (*BestMove, error) {
+	err := engine.SetFENPosition(fen)
+	if err != nil {
+		return nil, err
+	}
+	return engine.search(context.Background(), fmt.Sprintf("go depth %d", depth), nil)
+}
@@ -0,0 +1,54 @@
+package gostockfish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareEvaluations(t *testing.T) {
+	newSearchEngine := func(info string, bestMove string) *Engine {
+		engine, _ := newFakeEngine(func(command string) []string {
+			if strings.HasPrefix(command, "go depth 12") {
+				return []string{info, bestMove}
+			}
+			return nil
+		})
+		return engine
+	}
+
+	var tests = []struct {
+		a        *Engine
+		b        *Engine
+		expected bool
+	}{
+		{
+			newSearchEngine("info depth 12 seldepth 16 multipv 1 score cp 30 nodes 1000 nps 100000 tbhits 0 time 10 pv e2e4", "bestmove e2e4"),
+			newSearchEngine("info depth 12 seldepth 16 multipv 1 score cp 25 nodes 1000 nps 100000 tbhits 0 time 10 pv e2e4", "bestmove e2e4"),
+			true,
+		},
+		{
+			newSearchEngine("info depth 12 seldepth 16 multipv 1 score cp 30 nodes 1000 nps 100000 tbhits 0 time 10 pv e2e4", "bestmove e2e4"),
+			newSearchEngine("info depth 12 seldepth 16 multipv 1 score cp 28 nodes 1000 nps 100000 tbhits 0 time 10 pv d2d4", "bestmove d2d4"),
+			false,
+		},
+		{
+			newSearchEngine("info depth 12 seldepth 16 multipv 1 score mate 3 nodes 1000 nps 100000 tbhits 0 time 10 pv h5f7", "bestmove h5f7"),
+			newSearchEngine("info depth 12 seldepth 16 multipv 1 score mate 5 nodes 1000 nps 100000 tbhits 0 time 10 pv c4f7", "bestmove c4f7"),
+			true,
+		},
+		{
+			newSearchEngine("info depth 12 seldepth 16 multipv 1 score mate 3 nodes 1000 nps 100000 tbhits 0 time 10 pv h5f7", "bestmove h5f7"),
+			newSearchEngine("info depth 12 seldepth 16 multipv 1 score mate -4 nodes 1000 nps 100000 tbhits 0 time 10 pv c4f7", "bestmove c4f7"),
+			false,
+		},
+	}
+	for i, tt := range tests {
+		comparison, err := CompareEvaluations(StartFEN, 12, tt.a, tt.b)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if comparison.Agree != tt.expected {
+			t.Errorf("CompareEvaluations (%d): expected agreement %v, actual %v (%s, %s)", i, tt.expected, comparison.Agree, comparison.A.Move, comparison.B.Move)
+		}
+	}
+}
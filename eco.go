@@ -0,0 +1,123 @@
+package gostockfish
+
+import (
+	"strings"
+)
+
+// ECOEntry is an opening of the Encyclopaedia of Chess Openings: its code, name and the moves
+// from the standard starting position in standard algebraic notation, separated by spaces
+type ECOEntry struct {
+	Code  string
+	Name  string
+	Moves string
+}
+
+// ECOUnknown is the name returned by ECO for games which match no opening of the table
+const ECOUnknown string = "unknown"
+
+// ECOTable is the table of openings used by Match.ECO, a small selection of common lines. It
+// can be extended or replaced, see also Match.ECOWithTable.
+var ECOTable = []ECOEntry{
+	{"A04", "Reti Opening", "Nf3"},
+	{"A06", "Reti Opening", "Nf3 d5"},
+	{"A09", "Reti Opening", "Nf3 d5 c4"},
+	{"A10", "English Opening", "c4"},
+	{"A20", "English Opening", "c4 e5"},
+	{"A30", "English Opening: Symmetrical Variation", "c4 c5"},
+	{"A40", "Queen's Pawn Game", "d4"},
+	{"A45", "Indian Defense", "d4 Nf6"},
+	{"A46", "Indian Defense", "d4 Nf6 Nf3"},
+	{"A80", "Dutch Defense", "d4 f5"},
+	{"B00", "King's Pawn Game", "e4"},
+	{"B01", "Scandinavian Defense", "e4 d5"},
+	{"B02", "Alekhine Defense", "e4 Nf6"},
+	{"B06", "Modern Defense", "e4 g6"},
+	{"B07", "Pirc Defense", "e4 d6 d4 Nf6"},
+	{"B10", "Caro-Kann Defense", "e4 c6"},
+	{"B12", "Caro-Kann Defense: Advance Variation", "e4 c6 d4 d5 e5"},
+	{"B20", "Sicilian Defense", "e4 c5"},
+	{"B22", "Sicilian Defense: Alapin Variation", "e4 c5 c3"},
+	{"B27", "Sicilian Defense", "e4 c5 Nf3 g6"},
+	{"B30", "Sicilian Defense: Old Sicilian", "e4 c5 Nf3 Nc6"},
+	{"B40", "Sicilian Defense: French Variation", "e4 c5 Nf3 e6"},
+	{"B50", "Sicilian Defense", "e4 c5 Nf3 d6"},
+	{"B54", "Sicilian Defense: Open", "e4 c5 Nf3 d6 d4 cxd4 Nxd4"},
+	{"B90", "Sicilian Defense: Najdorf Variation", "e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6"},
+	{"C00", "French Defense", "e4 e6"},
+	{"C02", "French Defense: Advance Variation", "e4 e6 d4 d5 e5"},
+	{"C20", "King's Pawn Game", "e4 e5"},
+	{"C23", "Bishop's Opening", "e4 e5 Bc4"},
+	{"C30", "King's Gambit", "e4 e5 f4"},
+	{"C40", "King's Knight Opening", "e4 e5 Nf3"},
+	{"C41", "Philidor Defense", "e4 e5 Nf3 d6"},
+	{"C42", "Petrov's Defense", "e4 e5 Nf3 Nf6"},
+	{"C44", "King's Pawn Game", "e4 e5 Nf3 Nc6"},
+	{"C45", "Scotch Game", "e4 e5 Nf3 Nc6 d4 exd4 Nxd4"},
+	{"C46", "Three Knights Opening", "e4 e5 Nf3 Nc6 Nc3"},
+	{"C47", "Four Knights Game", "e4 e5 Nf3 Nc6 Nc3 Nf6"},
+	{"C50", "Italian Game", "e4 e5 Nf3 Nc6 Bc4"},
+	{"C53", "Italian Game: Giuoco Piano", "e4 e5 Nf3 Nc6 Bc4 Bc5 c3"},
+	{"C55", "Italian Game: Two Knights Defense", "e4 e5 Nf3 Nc6 Bc4 Nf6"},
+	{"C60", "Ruy Lopez", "e4 e5 Nf3 Nc6 Bb5"},
+	{"C65", "Ruy Lopez: Berlin Defense", "e4 e5 Nf3 Nc6 Bb5 Nf6"},
+	{"C68", "Ruy Lopez: Exchange Variation", "e4 e5 Nf3 Nc6 Bb5 a6 Bxc6"},
+	{"C70", "Ruy Lopez: Morphy Defense", "e4 e5 Nf3 Nc6 Bb5 a6 Ba4"},
+	{"D00", "Queen's Pawn Game", "d4 d5"},
+	{"D02", "Queen's Pawn Game", "d4 d5 Nf3"},
+	{"D06", "Queen's Gambit", "d4 d5 c4"},
+	{"D10", "Slav Defense", "d4 d5 c4 c6"},
+	{"D20", "Queen's Gambit Accepted", "d4 d5 c4 dxc4"},
+	{"D30", "Queen's Gambit Declined", "d4 d5 c4 e6"},
+	{"D35", "Queen's Gambit Declined", "d4 d5 c4 e6 Nc3 Nf6"},
+	{"D70", "Neo-Gruenfeld Defense", "d4 Nf6 c4 g6 f3 d5"},
+	{"D80", "Gruenfeld Defense", "d4 Nf6 c4 g6 Nc3 d5"},
+	{"E00", "Indian Defense", "d4 Nf6 c4 e6"},
+	{"E12", "Queen's Indian Defense", "d4 Nf6 c4 e6 Nf3 b6"},
+	{"E20", "Nimzo-Indian Defense", "d4 Nf6 c4 e6 Nc3 Bb4"},
+	{"E60", "King's Indian Defense", "d4 Nf6 c4 g6"},
+	{"E61", "King's Indian Defense", "d4 Nf6 c4 g6 Nc3 Bg7"},
+}
+
+// ECO classifies the opening of the match against ECOTable, see ECOWithTable
+func (match *Match) ECO() (code string, name string) {
+	return match.ECOWithTable(ECOTable)
+}
+
+// ECOWithTable classifies the opening of the match against the table: the entry with the
+// longest sequence of moves that the game started with wins. Games from another starting
+// position than the standard one, or matching no entry, are classified with an empty code as
+// ECOUnknown.
+func (match *Match) ECOWithTable(table []ECOEntry) (code string, name string) {
+	if match.StartFEN != "" && match.StartFEN != StartFEN {
+		return "", ECOUnknown
+	}
+	moves, err := match.MovesSAN()
+	if err != nil {
+		return "", ECOUnknown
+	}
+	for i, san := range moves {
+		// the check and mate markers are not part of table entries
+		moves[i] = strings.TrimRight(san, "+#")
+	}
+
+	best := -1
+	code, name = "", ECOUnknown
+	for _, entry := range table {
+		line := strings.Fields(entry.Moves)
+		if len(line) <= best || len(line) > len(moves) {
+			continue
+		}
+		matches := true
+		for i, san := range line {
+			if strings.TrimRight(san, "+#") != moves[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			best = len(line)
+			code, name = entry.Code, entry.Name
+		}
+	}
+	return code, name
+}
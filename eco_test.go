@@ -0,0 +1,43 @@
+package gostockfish
+
+import (
+	"testing"
+)
+
+func TestECOTable(t *testing.T) {
+	for _, entry := range ECOTable {
+		if _, _, err := ParsePGN(entry.Moves); err != nil {
+			t.Errorf("ECOTable %s %s: %s", entry.Code, entry.Name, err)
+		}
+	}
+}
+
+func TestECO(t *testing.T) {
+	var tests = []struct {
+		startFEN string
+		moves    []string
+		code     string
+		name     string
+	}{
+		{"", []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "a7a6", "b5a4", "g8f6"}, "C70", "Ruy Lopez: Morphy Defense"},
+		{"", []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5", "g8f6"}, "C65", "Ruy Lopez: Berlin Defense"},
+		{"", []string{"d2d4", "g8f6", "c2c4", "e7e6", "b1c3", "f8b4", "e2e3"}, "E20", "Nimzo-Indian Defense"},
+		{"", []string{"e2e4", "c7c5"}, "B20", "Sicilian Defense"},
+		{"", []string{"a2a3", "e7e5"}, "", ECOUnknown},
+		{"", nil, "", ECOUnknown},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", []string{"e2e4"}, "", ECOUnknown},
+	}
+	for _, tt := range tests {
+		m := &Match{StartFEN: tt.startFEN, Moves: tt.moves}
+		code, name := m.ECO()
+		if code != tt.code || name != tt.name {
+			t.Errorf("ECO(%v): expected %s %s, actual %s %s", tt.moves, tt.code, tt.name, code, name)
+		}
+	}
+
+	m := &Match{Moves: []string{"g2g3", "d7d5"}}
+	table := []ECOEntry{{"A00", "Hungarian Opening", "g3"}}
+	if code, name := m.ECOWithTable(table); code != "A00" || name != "Hungarian Opening" {
+		t.Errorf("ECOWithTable(%v): expected A00 Hungarian Opening, actual %s %s", m.Moves, code, name)
+	}
+}
@@ -0,0 +1,42 @@
+package gostockfish
+
+import (
+	"math"
+)
+
+// EloConfidence is the z-score of the confidence interval of EloDifference, 1.96 for 95%
+var EloConfidence = 1.96
+
+// EloDifference estimates the Elo difference from the wins, draws and losses of a series of
+// games, from the point of view of the winning side of wins. The score s = (wins + draws/2)
+// / games is mapped to Elo with the logistic model, -400 * log10(1/s - 1). The margin is half
+// the width of the confidence interval (see EloConfidence), derived from the standard error
+// of the score, i.e. 147 +/- 66 for 60 wins, 20 draws and 20 losses.
+//
+// Without games, both values are 0. If one side scored all points, the difference is
+// infinite. The margin is infinite if the confidence interval reaches a score of 0 or 1, i.e.
+// after too few games.
+func EloDifference(wins, draws, losses int) (elo float64, margin float64) {
+	games := float64(wins + draws + losses)
+	if games == 0 {
+		return 0, 0
+	}
+	score := (float64(wins) + float64(draws)/2) / games
+	if score == 0 || score == 1 {
+		return scoreToElo(score), math.Inf(1)
+	}
+
+	variance := (float64(wins)*math.Pow(1-score, 2) +
+		float64(draws)*math.Pow(0.5-score, 2) +
+		float64(losses)*math.Pow(score, 2)) / games
+	deviation := math.Sqrt(variance / games)
+
+	low := scoreToElo(math.Max(score-EloConfidence*deviation, 0))
+	high := scoreToElo(math.Min(score+EloConfidence*deviation, 1))
+	return scoreToElo(score), (high - low) / 2
+}
+
+// scoreToElo converts a score ratio in [0, 1] to an Elo difference
+func scoreToElo(score float64) float64 {
+	return -400 * math.Log10(1/score-1)
+}
@@ -0,0 +1,35 @@
+package gostockfish
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEloDifference(t *testing.T) {
+	var tests = []struct {
+		wins, draws, losses int
+		elo, margin         float64
+	}{
+		{60, 20, 20, 147.19, 66.01},
+		{200, 150, 100, 78.52, 26.59},
+		{100, 150, 200, -78.52, 26.59},
+		{50, 100, 50, 0, 34.16},
+		{10, 80, 10, 0, 30.53},
+		{0, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		elo, margin := EloDifference(tt.wins, tt.draws, tt.losses)
+		if math.Abs(elo-tt.elo) > 0.01 || math.Abs(margin-tt.margin) > 0.01 {
+			t.Errorf("EloDifference(%d, %d, %d): expected %.2f +/- %.2f, actual %.2f +/- %.2f", tt.wins, tt.draws, tt.losses, tt.elo, tt.margin, elo, margin)
+		}
+	}
+
+	elo, margin := EloDifference(10, 0, 0)
+	if !math.IsInf(elo, 1) || !math.IsInf(margin, 1) {
+		t.Errorf("EloDifference(10, 0, 0): expected infinite difference, actual %f +/- %f", elo, margin)
+	}
+	elo, _ = EloDifference(0, 0, 10)
+	if !math.IsInf(elo, -1) {
+		t.Errorf("EloDifference(0, 0, 10): expected negative infinite difference, actual %f", elo)
+	}
+}
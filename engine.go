@@ -2,14 +2,19 @@ package gostockfish
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // UCIMoveRegex describes the regular expression for UCI moves
@@ -18,6 +23,42 @@ const UCIMoveRegex string = `[a-h]\d[a-h]\d[qrnb]?`
 // PVRegex describe the regular expression for PV
 var PVRegex string = fmt.Sprintf(" pv (?P<move_list>%s( %s)*)", UCIMoveRegex, UCIMoveRegex)
 
+// DefaultReaderSize is the buffer size used to read the engine output unless Engine.ReaderSize is set
+const DefaultReaderSize int = 64 * 1024
+
+// WarningPatterns lists the (lowercase) phrases that mark an info string as a warning
+var WarningPatterns = []string{"warning", "error", "available processors", "thread"}
+
+// MaxPositionMoves is the maximum number of moves sent with a 'position' command. Some engines
+// truncate long input lines, which silently desyncs the position in very long games.
+var MaxPositionMoves = 200
+
+// randomSource is the source of randomness of the package (random Contempt, colors of NewMatch),
+// so the global source of math/rand is neither read nor perturbed
+var (
+	randomMu     sync.Mutex
+	randomSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// randomIntn returns a random number in [0, n) from randomSource
+func randomIntn(n int) int {
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	return randomSource.Intn(n)
+}
+
+// DefaultParam are the options set by the constructors (except NewEngineRaw) and ResetToDefaults
+var DefaultParam = map[string]string{
+	"Contempt":      "0",
+	"Threads":       "1",
+	"Hash":          "16",
+	"MultiPV":       "1",
+	"Skill Level":   "20",
+	"Move Overhead": "30",
+	"Slow Mover":    "80",
+	"UCI_Chess960":  "false",
+}
+
 // Engine is the chess engine with a UCI compatible interface (e.g. stockfish)
 type Engine struct {
 	Executable string
@@ -26,13 +67,69 @@ type Engine struct {
 	Depth      int
 	Ponder     bool
 	Param      map[string]string
+	ReaderSize int
+	// MaxSearchTime, if positive, caps the duration of any search: the engine is sent 'stop'
+	// once it is exceeded and the best move found so far is returned, which may be from a
+	// shallower depth than requested
+	MaxSearchTime time.Duration
+	// StrictOptions makes the startup fail if an option of Param cannot be set, otherwise
+	// such options are skipped and reported by FailedOptions
+	StrictOptions bool
+	// ResendPosition sends the current position again, followed by 'isready', before every
+	// 'go'. Some engines search a stale or empty position unless each 'go' directly follows a
+	// 'position' command, others drop or misparse commands sent while they are busy setting up
+	// the position. Not needed for stockfish.
+	ResendPosition bool
+	// ReadTimeout, if set, bounds the wait for each line of engine output; reads fail with
+	// ErrReadTimeout instead of hanging if the engine stops responding. During a search it
+	// must exceed the longest pause between info lines.
+	ReadTimeout time.Duration
+	// TrafficSize is the number of most recent lines sent to and received from the engine kept
+	// for RecentTraffic, i.e. to look into parse errors or a protocol desync. 0 keeps none.
+	TrafficSize int
+
+	options    map[string]Option
+	cmd        *exec.Cmd
+	exited     chan struct{}
+	waitErr    error
+	warnings   []string
+	sideToMove Color
+	raw        bool
+	applied    map[string]string
+	failed     map[string]error
+	name       string
+	author     string
+	debug      bool
+	debugLines []string
+	position   string
+	flipped    bool
+	reader     *lineReader
+	closed     bool
+	// lateReady is the number of 'readyok' still to arrive for fences which timed out
+	lateReady  int
+	trafficMu  sync.Mutex
+	traffic    []string
+	trafficPos int
+}
+
+// Option describes an option advertised by the engine during the uci handshake. Min and Max
+// bound the values of spin options, Var lists the values of combo options.
+type Option struct {
+	Name    string
+	Type    string
+	Default string
+	Min     int
+	Max     int
+	Var     []string
 }
 
-// BestMove contains info on the next best move
+// BestMove contains info on the next best move. The score of Info is from the point of view of
+// SideToMove, which is empty if the position was not set through one of the SetPosition methods.
 type BestMove struct {
-	Move   string
-	Ponder string
-	Info   *Info
+	Move       string
+	Ponder     string
+	Info       *Info
+	SideToMove Color
 }
 
 // Info describes a stockfish evaluation output
@@ -41,17 +138,226 @@ type Info struct {
 	Seldepth int
 	Multipv  int
 	Score    Score
-	Nodes    int
-	Nps      int
-	Tbhits   int
+	Nodes    int64
+	Nps      int64
+	Tbhits   int64
 	Time     int
 	Pv       string
+	WDL      WDL
+	// CurrMove is the root move the engine is searching and CurrMoveNumber its position in the
+	// move ordering, starting at 1, as reported during long searches
+	CurrMove       string
+	CurrMoveNumber int
+}
+
+// IsCurrMove reports whether the info is a progress report of the move currently searched,
+// i.e. "info depth 20 currmove e2e4 currmovenumber 1", without score and pv
+func (info *Info) IsCurrMove() bool {
+	return info.CurrMove != "" && info.Pv == ""
+}
+
+// SelectiveDepthGap returns seldepth - depth, how far the engine extended the search beyond
+// the nominal depth in selected lines. A large gap is a rough sign of a tactically sharp
+// position with many checks and captures to resolve, a small one of a quiet position.
+func (info *Info) SelectiveDepthGap() int {
+	return info.Seldepth - info.Depth
+}
+
+// AverageSelectiveDepthGap returns the mean SelectiveDepthGap of the info lines of a search,
+// i.e. those returned by BestMoveWithDepths, or 0 if there are none
+func AverageSelectiveDepthGap(infos []*Info) float64 {
+	if len(infos) == 0 {
+		return 0
+	}
+	total := 0
+	for _, info := range infos {
+		total += info.SelectiveDepthGap()
+	}
+	return float64(total) / float64(len(infos))
+}
+
+// TablebaseResult is the outcome of a position according to the endgame tablebases, from the
+// point of view of the side to move
+type TablebaseResult string
+
+// Tablebase results, see Info.TablebaseResult. A cursed win is won, but not within the fifty
+// move rule, a blessed loss is lost, but drawn by the fifty move rule.
+const (
+	TablebaseUnknown     TablebaseResult = ""
+	TablebaseWin         TablebaseResult = "win"
+	TablebaseCursedWin   TablebaseResult = "cursed win"
+	TablebaseDraw        TablebaseResult = "draw"
+	TablebaseBlessedLoss TablebaseResult = "blessed loss"
+	TablebaseLoss        TablebaseResult = "loss"
+)
+
+// TablebaseScore is the centipawn score stockfish reports for a tablebase win, reduced by the
+// distance in plies to the root of the search. Scores within TablebaseScoreRange of it are
+// classified as tablebase results.
+var TablebaseScore = 20000
+
+// TablebaseScoreRange is the maximum distance in plies from TablebaseScore, see TablebaseScore
+var TablebaseScoreRange = 1000
+
+// UsedTablebase reports whether the search consulted the endgame tablebases, i.e. tbhits > 0.
+// This requires the option 'SyzygyPath' to be set.
+func (info *Info) UsedTablebase() bool {
+	return info.Tbhits > 0
+}
+
+// TablebaseResult classifies the score of a search which used the tablebases. The heuristic
+// follows the scores stockfish reports for tablebase positions: TablebaseScore minus the
+// distance in plies for a win (negative for a loss), exactly 0 for a draw and a few
+// centipawns (at most 2) for a cursed win or blessed loss, which stockfish scores as draws
+// slightly in favour of the winning side. Mate scores are wins and losses. Any other score,
+// or a search without tbhits, is TablebaseUnknown: the tablebases did not decide the root.
+func (info *Info) TablebaseResult() TablebaseResult {
+	if !info.UsedTablebase() {
+		return TablebaseUnknown
+	}
+	value := info.Score.Value
+	if info.Score.Eval == "mate" {
+		if value > 0 {
+			return TablebaseWin
+		}
+		return TablebaseLoss
+	}
+	switch {
+	case value >= TablebaseScore-TablebaseScoreRange:
+		return TablebaseWin
+	case value <= -(TablebaseScore - TablebaseScoreRange):
+		return TablebaseLoss
+	case value == 0:
+		return TablebaseDraw
+	case value > 0 && value <= 2:
+		return TablebaseCursedWin
+	case value < 0 && value >= -2:
+		return TablebaseBlessedLoss
+	}
+	return TablebaseUnknown
+}
+
+// WDL is the win, draw and loss probability in permill from the point of view of the side to
+// move, reported by engines with the option 'UCI_ShowWDL' enabled
+type WDL struct {
+	Win  int
+	Draw int
+	Loss int
+}
+
+// SearchStats summarizes a completed search
+type SearchStats struct {
+	Depth    int
+	Seldepth int
+	Nodes    int64
+	Nps      int64
+	Elapsed  time.Duration
+}
+
+// Stats returns the statistics of the search, taken from the last info line before 'bestmove'.
+// Nps is averaged over the whole search where the engine reported the elapsed time.
+func (bestMove *BestMove) Stats() *SearchStats {
+	info := bestMove.Info
+	if info == nil {
+		return &SearchStats{}
+	}
+	stats := &SearchStats{
+		Depth:    info.Depth,
+		Seldepth: info.Seldepth,
+		Nodes:    info.Nodes,
+		Nps:      info.Nps,
+		Elapsed:  time.Duration(info.Time) * time.Millisecond,
+	}
+	if info.Time > 0 {
+		stats.Nps = info.Nodes * 1000 / int64(info.Time)
+	}
+	return stats
 }
 
-// Score describes the score of an evaluation
+// Score describes the score of an evaluation. Bound is "lower" or "upper" if the score is only
+// a lower or upper bound, as reported by searches failing high or low, and empty otherwise.
 type Score struct {
 	Eval  string
 	Value int
+	Bound string
+}
+
+// Pawns returns a centipawn score in pawns as reported by the engine, +/-Inf for mate scores
+// (+Inf if the side to move mates), see also Engine.NormalizedPawns
+func (score Score) Pawns() float64 {
+	if score.Eval == "mate" {
+		if score.Value > 0 {
+			return math.Inf(1)
+		}
+		return math.Inf(-1)
+	}
+	return float64(score.Value) / 100
+}
+
+// LegacyScoreScale converts the centipawns of stockfish versions before 15.1 to the normalized
+// centipawns of later versions. Up to 15, stockfish reported its internal score divided by the
+// endgame value of a pawn (208 internal units). Since 15.1 the score is normalized so that 100
+// centipawns mean a 50% chance to win, which took 361 internal units in 15.1. The conversion
+// is an approximation, the evaluation itself changed between versions, too.
+const LegacyScoreScale float64 = 208.0 / 361.0
+
+// NormalizedPawns returns the score in pawns on the scale of stockfish 15.1 and later, where a
+// pawn means a 50% chance to win, so scores of different stockfish versions can be compared.
+// The version is read from the engine name of the uci handshake (i.e. "Stockfish 14.1") and
+// scores of versions before 15.1 are scaled by LegacyScoreScale. Scores of development builds
+// and of other engines are returned unchanged, as by Score.Pawns.
+func (engine *Engine) NormalizedPawns(score Score) float64 {
+	pawns := score.Pawns()
+	if legacyScoreVersion(engine.name) {
+		pawns *= LegacyScoreScale
+	}
+	return pawns
+}
+
+// legacyScoreVersion reports whether the engine name is that of a stockfish release before
+// 15.1, which did not normalize its scores
+func legacyScoreVersion(name string) bool {
+	fields := strings.Fields(name)
+	if len(fields) < 2 || fields[0] != "Stockfish" {
+		return false
+	}
+	version := strings.SplitN(fields[1], ".", 2)
+	major, err := strconv.Atoi(version[0])
+	if err != nil {
+		// i.e. "Stockfish dev-20240101-4b4bd5e2"
+		return false
+	}
+	minor := 0
+	if len(version) == 2 {
+		minor, _ = strconv.Atoi(version[1])
+	}
+	return major < 15 || (major == 15 && minor < 1)
+}
+
+// Checkmated reports whether the score is "mate 0", given for a position in which the side to
+// move is checkmated already. Unlike other mate scores it describes no search result: there is
+// no move left to search.
+func (score Score) Checkmated() bool {
+	return score.Eval == "mate" && score.Value == 0
+}
+
+// Checkmated reports whether the side to move was checkmated in the searched position, i.e.
+// the engine answered "info depth 0 score mate 0" and "bestmove (none)"
+func (bestMove *BestMove) Checkmated() bool {
+	return bestMove.Move == "(none)" && bestMove.Info != nil && bestMove.Info.Score.Checkmated()
+}
+
+// Evaluation describes the static evaluation printed by the 'eval' command. All values
+// are in pawns from white's point of view.
+type Evaluation struct {
+	Final float64
+	Terms map[string]EvalTerm
+}
+
+// EvalTerm is the total middlegame and endgame contribution of a single evaluation term
+type EvalTerm struct {
+	MG float64
+	EG float64
 }
 
 // NewEngine initiates the Stockfish chess engine with Ponder set to false.
@@ -86,236 +392,2371 @@ func NewEngineWithDepth(depth int) (*Engine, error) {
 // 'randMin' and 'randMax' so that you may run automated matches against slightly different
 // engines.
 func NewEngineWithAllOptions(stockfishExecutable string, depth int, ponder bool, param map[string]string, random bool, randMin int, randMax int) (*Engine, error) {
-	engine := &Engine{
-		Executable: stockfishExecutable,
-		Depth:      depth,
-		Ponder:     ponder,
-		Param:      param,
+	engine := newEngine(stockfishExecutable, depth, ponder, param, random, randMin, randMax)
+
+	err := engine.start()
+	if err != nil {
+		return nil, err
 	}
 
-	cmd := exec.Command(stockfishExecutable)
+	return engine, nil
+}
 
-	stdin, err := cmd.StdinPipe()
+// NewEngineWithReaderSize initiates the Stockfish chess engine with the given depth, reading
+// its output with a buffer of readerSize bytes. Info lines longer than the buffer (long PVs
+// at high depth with MultiPV) are read in several chunks, a larger buffer avoids that.
+func NewEngineWithReaderSize(depth int, readerSize int) (*Engine, error) {
+	engine := newEngine("stockfish", depth, false, map[string]string{}, false, -10, 10)
+	engine.ReaderSize = readerSize
+
+	err := engine.start()
 	if err != nil {
 		return nil, err
 	}
-	engine.Stdin = &stdin
 
-	stdout, err := cmd.StdoutPipe()
+	return engine, nil
+}
+
+// NewEngineRaw initiates a UCI chess engine without applying any defaults: only the uci
+// handshake is performed and the options in 'param' are set, all other options keep the
+// engine's own defaults. Use this for engines whose options differ from Stockfish's.
+func NewEngineRaw(executable string, depth int, param map[string]string) (*Engine, error) {
+	engine := &Engine{
+		Executable: executable,
+		Depth:      depth,
+		Param:      map[string]string{},
+		raw:        true,
+	}
+	for name, value := range param {
+		engine.Param[name] = value
+	}
+
+	err := engine.start()
 	if err != nil {
 		return nil, err
 	}
 
-	cmd.Start()
-
-	engine.Stdout = bufio.NewReader(stdout)
+	return engine, nil
+}
 
-	engine.Put("uci")
+// NewEngineStrict initiates the Stockfish chess engine like NewEngineWithAllOptions, but fails
+// if any option, including the defaults, cannot be set (see Engine.StrictOptions)
+func NewEngineStrict(stockfishExecutable string, depth int, param map[string]string) (*Engine, error) {
+	engine := newEngine(stockfishExecutable, depth, false, param, false, -10, 10)
+	engine.StrictOptions = true
 
-	if !ponder {
-		engine.SetOption("Ponder", "false")
+	err := engine.start()
+	if err != nil {
+		return nil, err
 	}
 
-	baseParam := map[string]string{
-		"Contempt":      "0",
-		"Threads":       "1",
-		"Hash":          "16",
-		"MultiPV":       "1",
-		"Skill Level":   "20",
-		"Move Overhead": "30",
-		"Slow Mover":    "80",
-		"UCI_Chess960":  "false",
+	return engine, nil
+}
+
+// newEngine returns an engine which has not been started yet, with the default parameters
+// merged with 'param'
+func newEngine(stockfishExecutable string, depth int, ponder bool, param map[string]string, random bool, randMin int, randMax int) *Engine {
+	baseParam := map[string]string{}
+	for name, value := range DefaultParam {
+		baseParam[name] = value
 	}
 
 	if random {
-		baseParam["Contempt"] = strconv.Itoa(rand.Intn(randMax-randMin) + randMin)
+		baseParam["Contempt"] = strconv.Itoa(randomIntn(randMax-randMin) + randMin)
 	}
 
 	for name, value := range param {
 		baseParam[name] = value
 	}
-	engine.Param = baseParam
 
-	for name, value := range engine.Param {
-		err = engine.SetOption(name, value)
-		if err != nil {
-			return nil, err
-		}
+	return &Engine{
+		Executable: stockfishExecutable,
+		Depth:      depth,
+		Ponder:     ponder,
+		Param:      baseParam,
+	}
+}
+
+// NewEngineContext initiates the Stockfish chess engine like NewEngineWithAllOptions, but gives
+// up once ctx is done: the engine process is killed and an error wrapping ctx.Err() is returned.
+// ctx only bounds the startup, the engine keeps running after NewEngineContext returns.
+func NewEngineContext(ctx context.Context, stockfishExecutable string, depth int, ponder bool, param map[string]string, random bool, randMin int, randMax int) (*Engine, error) {
+	engine := newEngine(stockfishExecutable, depth, ponder, param, random, randMin, randMax)
+
+	err := engine.startContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	return engine, nil
 }
 
-// Put command to chess engine
-func (engine *Engine) Put(command string) {
-	io.WriteString(*engine.Stdin, command+"\n")
+// start spawns the engine process, performs the uci handshake and applies Ponder and Param
+func (engine *Engine) start() error {
+	return engine.startContext(context.Background())
 }
 
-// SetOption sets an engine option
-func (engine *Engine) SetOption(optionName string, value string) error {
-	engine.Put(fmt.Sprintf("setoption name %s value %s", optionName, value))
-	return engine.IsReady()
+// startContext is like start, but kills the engine process if the startup fails or ctx is done first
+func (engine *Engine) startContext(ctx context.Context) error {
+	err := engine.spawn()
+	if err != nil {
+		return err
+	}
+
+	initialized := make(chan error, 1)
+	go func() {
+		initialized <- engine.initialize()
+	}()
+
+	select {
+	case err = <-initialized:
+	case <-ctx.Done():
+		err = fmt.Errorf("Engine startup aborted: %w", ctx.Err())
+	}
+	if err != nil {
+		engine.cmd.Process.Kill()
+		<-engine.exited
+		return err
+	}
+	return nil
 }
 
-// IsReady is used to synchronize the golang engine object with the back-end engine. Sends 'isready' and waits for 'readyok.'
-func (engine *Engine) IsReady() error {
-	engine.Put("isready")
-	for {
-		text, _, err := engine.Stdout.ReadLine()
-		if err != nil {
-			return err
+// spawn starts the engine process and connects Stdin and Stdout
+func (engine *Engine) spawn() error {
+	cmd := exec.Command(engine.Executable)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	engine.Stdin = &stdin
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	engine.cmd = cmd
+	engine.exited = make(chan struct{})
+	go func(exited chan struct{}) {
+		engine.waitErr = cmd.Wait()
+		close(exited)
+	}(engine.exited)
+
+	readerSize := engine.ReaderSize
+	if readerSize <= 0 {
+		readerSize = DefaultReaderSize
+	}
+	engine.Stdout = bufio.NewReaderSize(stdout, readerSize)
+	engine.warnings = nil
+	engine.sideToMove = ""
+	engine.position, engine.flipped = "", false
+	engine.closed = false
+	engine.lateReady = 0
+
+	return nil
+}
+
+// initialize performs the uci handshake and applies Ponder and Param
+func (engine *Engine) initialize() error {
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		return err
+	}
+	err = engine.checkCopyProtection()
+	if err != nil {
+		return err
+	}
+
+	return engine.applyParam()
+}
+
+// PriorityOptions are set before all other options of Engine.Param, in this order. Changing
+// Threads reallocates the hash table, so it goes first, followed by Hash. The other options
+// are set in alphabetical order.
+var PriorityOptions = []string{"Threads", "Hash"}
+
+// applyParam sets Ponder and the options of engine.Param, taking note of the applied and failed
+// options. The options are set in a fixed order, see PriorityOptions.
+func (engine *Engine) applyParam() error {
+	if !engine.Ponder && !engine.raw {
+		engine.SetOption("Ponder", "false")
+	}
+
+	var names []string
+	for name := range engine.Param {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := optionPriority(names[i]), optionPriority(names[j])
+		if pi != pj {
+			return pi < pj
 		}
-		line := strings.TrimSpace(string(text))
-		if strings.Contains(line, "No such option:") {
-			return errors.New(line)
-		} else if strings.Contains(line, "Unknown command:") {
-			return errors.New(line)
+		return names[i] < names[j]
+	})
+
+	engine.applied = map[string]string{}
+	engine.failed = map[string]error{}
+	for _, name := range names {
+		value := engine.Param[name]
+		err := engine.validateOption(name, value)
+		if err == nil {
+			err = engine.SetOption(name, value)
+			if _, rejected := err.(*CommandError); err != nil && !rejected {
+				return err
+			}
 		}
-		if line == "readyok" {
-			return nil
+		if err != nil {
+			engine.failed[name] = err
+			if engine.StrictOptions {
+				return fmt.Errorf("Could not set option %s: %s", name, err.Error())
+			}
+			continue
 		}
+		engine.applied[name] = value
 	}
-}
 
-// NewGame calls 'ucinewgame' - this should be run before a new game
-func (engine *Engine) NewGame() error {
-	engine.Put("ucinewgame")
-	return engine.IsReady()
+	return nil
 }
 
-// SetPosition sets start position to list of moves (i.e. ['e2e4', 'e7e5', ...]).  Moves must be in full algebraic notation.
-func (engine *Engine) SetPosition(moves []string) error {
-	engine.Put(fmt.Sprintf("position startpos moves %s", strings.Join(moves, " ")))
-	return engine.IsReady()
+// optionPriority returns the position of the option in PriorityOptions, or len(PriorityOptions)
+// for any other option
+func optionPriority(name string) int {
+	for i, priority := range PriorityOptions {
+		if strings.EqualFold(name, priority) {
+			return i
+		}
+	}
+	return len(PriorityOptions)
 }
 
-// SetFENPosition sets start position in FEN notation. Input is a FEN string i.e. "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
-func (engine *Engine) SetFENPosition(fen string) error {
-	engine.Put(fmt.Sprintf("position fen %s", fen))
-	return engine.IsReady()
+// AppliedOptions returns the options of engine.Param the engine accepted at startup
+func (engine *Engine) AppliedOptions() map[string]string {
+	applied := map[string]string{}
+	for name, value := range engine.applied {
+		applied[name] = value
+	}
+	return applied
 }
 
-// Go starts calculating on the current position
-func (engine *Engine) Go() error {
-	engine.Put(fmt.Sprintf("go depth %s", strconv.Itoa(engine.Depth)))
-	return engine.IsReady()
+// FailedOptions returns the options of engine.Param which could not be set at startup, because
+// the engine does not advertise them, the value does not match the option type or the engine
+// rejected them
+func (engine *Engine) FailedOptions() map[string]error {
+	failed := map[string]error{}
+	for name, err := range engine.failed {
+		failed[name] = err
+	}
+	return failed
 }
 
-// BestMove gets the proposed best move for current position.
-func (engine *Engine) BestMove() (*BestMove, error) {
-	var lastInfo *Info
+// IsAlive reports whether the engine process is still running
+func (engine *Engine) IsAlive() bool {
+	if engine.exited == nil {
+		return false
+	}
+	select {
+	case <-engine.exited:
+		return false
+	default:
+		return true
+	}
+}
 
-	engine.Go()
+// Restart kills the engine process, if still running, and starts a fresh one with the same
+// Executable, Depth, Ponder and Param. The current position is lost and must be set again
+// by the caller, e.g. with SetPosition.
+func (engine *Engine) Restart() error {
+	if engine.IsAlive() {
+		engine.cmd.Process.Kill()
+		<-engine.exited
+	}
+	return engine.start()
+}
 
-	for {
-		text, _, err := engine.Stdout.ReadLine()
-		if err != nil {
-			return nil, err
+// ResetToDefaults re-applies DefaultParam and calls 'ucinewgame', returning the running engine
+// to a well-defined baseline without spawning a new process (see Restart). Engines created
+// with NewEngineRaw have the options of engine.Param reset to the defaults they advertise.
+// Other engines have the options of engine.Param not in DefaultParam (i.e. set by SetAnalyseMode
+// or SetEloLimit) reset to the defaults they advertise first.
+func (engine *Engine) ResetToDefaults() error {
+	param := map[string]string{}
+	if engine.raw {
+		for name := range engine.Param {
+			if option, ok := engine.option(name); ok && option.Type != "button" {
+				param[option.Name] = option.Default
+			}
 		}
-		line := strings.TrimSpace(string(text))
-		splitText := strings.Split(line, " ")
-		if splitText[0] == "info" {
-			lastInfo, err = ParseInfo(line)
-			if err != nil {
-				return nil, err
+	} else {
+		var names []string
+		for name := range engine.Param {
+			if _, ok := DefaultParam[name]; !ok {
+				names = append(names, name)
 			}
 		}
-		if splitText[0] == "bestmove" {
-			bestMove, err := ParseBestMove(line)
-			if err != nil {
-				return nil, err
+		sort.Strings(names)
+		for _, name := range names {
+			option, ok := engine.option(name)
+			if !ok || option.Type == "button" {
+				continue
 			}
-			bestMove.Info = lastInfo
-			return bestMove, nil
+			err := engine.SetOption(option.Name, option.Default)
+			if _, rejected := err.(*CommandError); err != nil && !rejected {
+				return err
+			}
+		}
+		for name, value := range DefaultParam {
+			param[name] = value
 		}
 	}
+	engine.Param = param
+
+	err := engine.applyParam()
+	if err != nil {
+		return err
+	}
+	return engine.NewGame()
 }
 
-// ParseInfo parses stockfish evaluation output
-//
-// Examples of input:
-// "info depth 2 seldepth 3 multipv 1 score cp -656 nodes 43 nps 43000 tbhits 0 time 1 pv g7g6 h3g3 g6f7"
-// "info depth 10 seldepth 12 multipv 1 score mate 5 nodes 2378 nps 1189000 tbhits 0 time 2 pv h3g3 g6f7 g3c7 b5d7 d1d7 f7g6 c7g3 g6h5 e6f4"
-func ParseInfo(line string) (*Info, error) {
-	var err error
-	result := &Info{}
+// Clone starts another process of the same engine with the same Executable, Depth, Ponder,
+// Param, ReaderSize, MaxSearchTime, StrictOptions, ResendPosition, ReadTimeout and
+// TrafficSize, i.e. to let an engine play against itself
+func (engine *Engine) Clone() (*Engine, error) {
+	clone := &Engine{
+		Executable: engine.Executable,
+		Depth:      engine.Depth,
+		Ponder:     engine.Ponder,
+		Param:      map[string]string{},
+		ReaderSize: engine.ReaderSize,
+		raw:        engine.raw,
 
-	info := regexp.MustCompile("info string [^ ]+ evaluation using [^ ]+ enabled")
-	matches := info.FindAllStringSubmatch(line, -1)
-	if matches != nil {
-		return result, nil
+		MaxSearchTime:  engine.MaxSearchTime,
+		StrictOptions:  engine.StrictOptions,
+		ResendPosition: engine.ResendPosition,
+		ReadTimeout:    engine.ReadTimeout,
+		TrafficSize:    engine.TrafficSize,
 	}
-
-	pv := regexp.MustCompile(PVRegex)
-	matches = pv.FindAllStringSubmatch(line, -1)
-	if matches == nil {
-		return nil, fmt.Errorf("Could not parse pv: %s", line)
+	for name, value := range engine.Param {
+		clone.Param[name] = value
 	}
-	result.Pv = matches[0][1]
 
-	// Example values:
-	// score cp -100        <- engine is behind 100 centipawns
-	// score mate 3         <- engine has big lead or checkmated opponent
-	score := regexp.MustCompile(`score (?P<eval>\w+) (?P<value>-?\d+)`)
-	matches = score.FindAllStringSubmatch(line, -1)
-	if matches == nil {
-		return nil, fmt.Errorf("Could not parse score: %s", line)
-	}
-	result.Score.Eval = matches[0][1]
-	result.Score.Value, err = strconv.Atoi(matches[0][2])
+	err := clone.start()
 	if err != nil {
 		return nil, err
 	}
 
-	singleValueFields := []string{"depth", "seldepth", "multipv", "nodes", "nps", "tbhits", "time"}
-	for _, field := range singleValueFields {
-		search := regexp.MustCompile(field + ` (?P<value>\d+)`)
-		matches = search.FindAllStringSubmatch(line, -1)
-		if matches == nil {
-			return nil, fmt.Errorf("Could not parse %s: %s", field, line)
-		}
-		value, err := strconv.Atoi(matches[0][1])
-		if err != nil {
-			return nil, err
+	return clone, nil
+}
+
+// Close sends 'quit', closes Stdin and waits for the engine process to exit, killing it if it
+// does not exit within a second. Returns the error of the process exit, see exec.Cmd.Wait.
+// Calling Close again does nothing and returns nil.
+func (engine *Engine) Close() error {
+	if engine.closed {
+		return nil
+	}
+	engine.closed = true
+	if engine.reader != nil {
+		close(engine.reader.done)
+		engine.reader = nil
+	}
+	if !engine.IsAlive() {
+		if engine.Stdin != nil {
+			(*engine.Stdin).Close()
 		}
-		if field == "depth" {
-			result.Depth = value
-		} else if field == "seldepth" {
-			result.Seldepth = value
-		} else if field == "multipv" {
-			result.Multipv = value
-		} else if field == "nodes" {
-			result.Nodes = value
-		} else if field == "nps" {
-			result.Nps = value
-		} else if field == "tbhits" {
-			result.Tbhits = value
-		} else if field == "time" {
-			result.Time = value
+		if engine.exited == nil {
+			return nil
 		}
+		return engine.waitErr
 	}
 
-	return result, nil
+	engine.Put("quit")
+	(*engine.Stdin).Close()
+	select {
+	case <-engine.exited:
+	case <-time.After(time.Second):
+		engine.cmd.Process.Kill()
+		<-engine.exited
+	}
+	return engine.waitErr
 }
 
-// ParseBestMove parses stockfish bestmove output
-//
-// Examples of input:
-// "bestmove d2d4 ponder a7a6"
-//
-func ParseBestMove(line string) (*BestMove, error) {
-	var ponder string
+// Put command to chess engine
+func (engine *Engine) Put(command string) {
+	engine.record("> " + command)
+	io.WriteString(*engine.Stdin, command+"\n")
+}
+
+// record keeps the line of traffic for RecentTraffic, dropping the oldest one once
+// engine.TrafficSize lines are kept
+func (engine *Engine) record(line string) {
+	if engine.TrafficSize <= 0 {
+		return
+	}
+	engine.trafficMu.Lock()
+	defer engine.trafficMu.Unlock()
+	if cap(engine.traffic) != engine.TrafficSize {
+		// first use or resized, start over
+		engine.traffic = make([]string, 0, engine.TrafficSize)
+		engine.trafficPos = 0
+	}
+	if len(engine.traffic) < engine.TrafficSize {
+		engine.traffic = append(engine.traffic, line)
+		return
+	}
+	engine.traffic[engine.trafficPos] = line
+	engine.trafficPos = (engine.trafficPos + 1) % engine.TrafficSize
+}
+
+// RecentTraffic returns up to engine.TrafficSize of the most recent lines sent to the engine,
+// prefixed with "> ", and received from the engine, prefixed with "< ", oldest first
+func (engine *Engine) RecentTraffic() []string {
+	engine.trafficMu.Lock()
+	defer engine.trafficMu.Unlock()
+	return append(append([]string{}, engine.traffic[engine.trafficPos:]...), engine.traffic[:engine.trafficPos]...)
+}
 
-	splitText := strings.Split(line, " ")
+// ErrReadTimeout is returned when the engine sends no line within Engine.ReadTimeout
+var ErrReadTimeout = errors.New("Timed out reading engine output")
 
-	if len(splitText) < 3 {
-		ponder = ""
-	} else {
-		ponder = splitText[3]
+// lineReader reads the lines of the engine output in the background, so reads can be
+// abandoned once a context is done or a timeout expires
+type lineReader struct {
+	from  *bufio.Reader
+	lines chan lineResult
+	done  chan struct{}
+}
+
+// lineResult is a line read by a lineReader, or the error which ended reading
+type lineResult struct {
+	text string
+	err  error
+}
+
+// newLineReader starts reading the lines of from until an error occurs or done is closed
+func newLineReader(from *bufio.Reader) *lineReader {
+	reader := &lineReader{
+		from:  from,
+		lines: make(chan lineResult),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		for {
+			text, err := readRawLine(from)
+			select {
+			case reader.lines <- lineResult{text: text, err: err}:
+			case <-reader.done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return reader
+}
+
+// readRawLine reads the next line of from, also if it is longer than the reader's buffer
+func readRawLine(from *bufio.Reader) (string, error) {
+	text, isPrefix, err := from.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	if isPrefix {
+		// the line is longer than the reader's buffer, collect the remaining chunks
+		text = append([]byte{}, text...)
+		for isPrefix {
+			var more []byte
+			more, isPrefix, err = from.ReadLine()
+			if err != nil {
+				return "", err
+			}
+			text = append(text, more...)
+		}
+	}
+	return string(text), nil
+}
+
+// readLine reads the next line of engine output and takes note of warnings reported as info
+// strings. Blank lines are skipped. Returns ctx.Err() once ctx is done and ErrReadTimeout if
+// engine.ReadTimeout passes without a line; the line is not lost but returned by the next read.
+func (engine *Engine) readLine(ctx context.Context) (string, error) {
+	if engine.reader == nil || engine.reader.from != engine.Stdout {
+		if engine.reader != nil {
+			close(engine.reader.done)
+		}
+		engine.reader = newLineReader(engine.Stdout)
+	}
+
+	var timeout <-chan time.Time
+	if engine.ReadTimeout > 0 {
+		timer := time.NewTimer(engine.ReadTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		var result lineResult
+		select {
+		case result = <-engine.reader.lines:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timeout:
+			return "", ErrReadTimeout
+		}
+		if result.err != nil {
+			return "", result.err
+		}
+		engine.record("< " + result.text)
+		line := strings.TrimSpace(result.text)
+		if line != "" {
+			engine.inspectLine(line)
+			return line, nil
+		}
+	}
+}
+
+// inspectLine takes note of warnings and debug output
+func (engine *Engine) inspectLine(line string) {
+	if strings.HasPrefix(line, "info string ") {
+		message := strings.TrimPrefix(line, "info string ")
+		lower := strings.ToLower(message)
+		for _, pattern := range WarningPatterns {
+			if strings.Contains(lower, pattern) {
+				engine.warnings = append(engine.warnings, message)
+				break
+			}
+		}
+	}
+	if engine.debug && isDebugLine(line) {
+		engine.debugLines = append(engine.debugLines, line)
+	}
+}
+
+// uciResponses are the first words of the lines of the UCI protocol sent by an engine, other
+// than info strings
+var uciResponses = []string{"id", "uciok", "readyok", "bestmove", "copyprotection", "registration", "info", "option"}
+
+// isDebugLine reports whether the line is diagnostic output rather than part of the UCI
+// protocol, i.e. an info string or plain text
+func isDebugLine(line string) bool {
+	if strings.HasPrefix(line, "info string ") {
+		return true
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	for _, response := range uciResponses {
+		if fields[0] == response {
+			return false
+		}
+	}
+	return true
+}
+
+// SetDebug switches the debug mode of the engine with 'debug on' or 'debug off'. While debug
+// mode is on, the diagnostic output of the engine (info strings and lines which are not part
+// of the UCI protocol) is captured, see DebugLines. Switching it on discards the lines
+// captured before.
+func (engine *Engine) SetDebug(on bool) error {
+	engine.debug = on
+	if on {
+		engine.debugLines = nil
+		engine.Put("debug on")
+	} else {
+		engine.Put("debug off")
+	}
+	return engine.IsReady()
+}
+
+// DebugLines returns the diagnostic output captured since debug mode was switched on with
+// SetDebug
+func (engine *Engine) DebugLines() []string {
+	return append([]string{}, engine.debugLines...)
+}
+
+// Warnings returns the warnings the engine reported as info strings, i.e. about the number
+// of available processors when Threads is set too high
+func (engine *Engine) Warnings() []string {
+	return append([]string{}, engine.warnings...)
+}
+
+// waitForUCIOK reads the engine's reply to 'uci' up to 'uciok' and records the advertised options
+func (engine *Engine) waitForUCIOK() error {
+	engine.options = map[string]Option{}
+	engine.name, engine.author = "", ""
+	for {
+		line, err := engine.readLine(context.Background())
+		if err != nil {
+			return err
+		}
+		// other lines, i.e. info strings about loading the network, are skipped
+		if line == "uciok" {
+			return nil
+		}
+		if strings.HasPrefix(line, "id name ") {
+			engine.name = strings.TrimPrefix(line, "id name ")
+		} else if strings.HasPrefix(line, "id author ") {
+			engine.author = strings.TrimPrefix(line, "id author ")
+		}
+		if line == "copyprotection error" {
+			return ErrCopyProtection
+		}
+		if strings.HasPrefix(line, "option ") {
+			option, err := ParseOption(line)
+			if err != nil {
+				return err
+			}
+			engine.options[option.Name] = *option
+		}
+	}
+}
+
+// ErrCopyProtection is returned when a copy protected engine reports 'copyprotection error'
+var ErrCopyProtection = errors.New("Engine copy protection check failed")
+
+// checkCopyProtection synchronizes with the engine after the uci handshake. Copy protected
+// engines send 'copyprotection checking' after 'uciok', followed by 'copyprotection ok' or
+// 'copyprotection error' once done; other engines just answer 'readyok'.
+func (engine *Engine) checkCopyProtection() error {
+	engine.Put("isready")
+	ready, checking := false, false
+	for !ready || checking {
+		line, err := engine.readLine(context.Background())
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "readyok":
+			ready = true
+		case "copyprotection checking":
+			checking = true
+		case "copyprotection ok":
+			checking = false
+		case "copyprotection error":
+			return ErrCopyProtection
+		}
+	}
+	return nil
+}
+
+// Name returns the engine name sent during the uci handshake, i.e. "Stockfish 12"
+func (engine *Engine) Name() string {
+	return engine.name
+}
+
+// Author returns the engine author sent during the uci handshake
+func (engine *Engine) Author() string {
+	return engine.author
+}
+
+// HasOption reports whether the engine advertised the given option during the uci handshake.
+// Option names are matched case-insensitively, like Stockfish does.
+func (engine *Engine) HasOption(name string) bool {
+	_, ok := engine.option(name)
+	return ok
+}
+
+// Options returns the options the engine advertised during the uci handshake by name
+func (engine *Engine) Options() map[string]Option {
+	options := map[string]Option{}
+	for name, option := range engine.options {
+		option.Var = append([]string(nil), option.Var...)
+		options[name] = option
+	}
+	return options
+}
+
+// option looks up an option advertised by the engine, matching the name case-insensitively
+func (engine *Engine) option(name string) (Option, bool) {
+	if option, ok := engine.options[name]; ok {
+		return option, true
+	}
+	for _, option := range engine.options {
+		if strings.EqualFold(option.Name, name) {
+			return option, true
+		}
+	}
+	return Option{}, false
+}
+
+// SetOption sets an engine option and waits for the engine to be ready. The value of button
+// options (i.e. 'Clear Hash') is ignored, see PressButton.
+func (engine *Engine) SetOption(optionName string, value string) error {
+	engine.SetOptionNoWait(optionName, value)
+	return engine.IsReady()
+}
+
+// SetOptionVerified sets an engine option like SetOption, but returns an error if the option
+// did not take effect: if the engine did not advertise it during the uci handshake (i.e. a
+// typo in the name, which some engines silently ignore), if the value does not match the
+// option type or if the engine rejected it. UCI offers no way to read options back, so
+// engines which advertised no options at all are treated like SetOption.
+func (engine *Engine) SetOptionVerified(optionName string, value string) error {
+	err := engine.validateOption(optionName, value)
+	if err != nil {
+		return fmt.Errorf("Could not set option %s: %s", optionName, err.Error())
+	}
+	return engine.SetOption(optionName, value)
+}
+
+// SetOptionNoWait sets an engine option without synchronizing with 'isready', for engines
+// which apply options asynchronously or answer 'readyok' late. The next command waiting for
+// the engine, i.e. IsReady, also waits for the option to be applied.
+func (engine *Engine) SetOptionNoWait(optionName string, value string) {
+	if option, _ := engine.option(optionName); option.Type == "button" {
+		// buttons take no value, "setoption name Clear Hash value " is malformed
+		engine.Put(fmt.Sprintf("setoption name %s", optionName))
+		return
+	}
+	engine.Put(fmt.Sprintf("setoption name %s value %s", optionName, value))
+}
+
+// ConfigError lists the options of a config which could not be applied, see ApplyConfig
+type ConfigError struct {
+	Errors map[string]error
+}
+
+func (err *ConfigError) Error() string {
+	var names []string
+	for name := range err.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var messages []string
+	for _, name := range names {
+		messages = append(messages, fmt.Sprintf("%s: %s", name, err.Errors[name].Error()))
+	}
+	return fmt.Sprintf("Could not apply %d options: %s", len(names), strings.Join(messages, "; "))
+}
+
+// ApplyConfig sets a batch of options, i.e. loaded from a config file, and waits for the engine
+// to be ready. Options which the engine does not advertise or whose value does not match the
+// option type are skipped and reported together in a *ConfigError, the others are applied and
+// recorded in engine.Param.
+func (engine *Engine) ApplyConfig(config map[string]string) error {
+	var names []string
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := map[string]error{}
+	for _, name := range names {
+		value := config[name]
+		err := engine.validateOption(name, value)
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+		engine.SetOptionNoWait(name, value)
+		engine.Param[name] = value
+	}
+
+	err := engine.IsReady()
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return &ConfigError{Errors: failed}
+	}
+	return nil
+}
+
+// validateOption checks a value against the option advertised by the engine. Any option is
+// accepted if the engine advertised none.
+func (engine *Engine) validateOption(name string, value string) error {
+	if len(engine.options) == 0 {
+		return nil
+	}
+	option, ok := engine.option(name)
+	if !ok {
+		return errors.New("Engine does not support option")
+	}
+	switch option.Type {
+	case "check":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("Invalid value %s, expected true or false", value)
+		}
+	case "spin":
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("Invalid value %s, expected an integer", value)
+		}
+		if option.Min < option.Max && (number < option.Min || number > option.Max) {
+			return fmt.Errorf("Invalid value %s, expected %d to %d", value, option.Min, option.Max)
+		}
+	case "combo":
+		for _, v := range option.Var {
+			if strings.EqualFold(v, value) {
+				return nil
+			}
+		}
+		if len(option.Var) > 0 {
+			return fmt.Errorf("Invalid value %s, expected one of %s", value, strings.Join(option.Var, ", "))
+		}
+	}
+	return nil
+}
+
+// PressButton sends a button option advertised by the engine, i.e. 'Clear Hash'
+func (engine *Engine) PressButton(optionName string) error {
+	option, ok := engine.option(optionName)
+	if !ok || option.Type != "button" {
+		return fmt.Errorf("Engine does not support button %s", optionName)
+	}
+	engine.Put(fmt.Sprintf("setoption name %s", optionName))
+	return engine.IsReady()
+}
+
+// SetAnalyseMode switches the engine between analysis and play. Analysis mode sets
+// 'UCI_AnalyseMode' to true and 'Contempt' to 0, as contempt only makes sense when
+// playing against an opponent. Switching back restores 'Contempt' from engine.Param.
+func (engine *Engine) SetAnalyseMode(analyse bool) error {
+	if !engine.HasOption("UCI_AnalyseMode") {
+		return errors.New("Engine does not support option UCI_AnalyseMode")
+	}
+	err := engine.SetOption("UCI_AnalyseMode", strconv.FormatBool(analyse))
+	if err != nil {
+		return err
+	}
+	engine.Param["UCI_AnalyseMode"] = strconv.FormatBool(analyse)
+
+	if !engine.HasOption("Contempt") {
+		return nil
+	}
+	contempt := "0"
+	if !analyse {
+		contempt = engine.Param["Contempt"]
+		if contempt == "" {
+			contempt = "0"
+		}
+	}
+	return engine.SetOption("Contempt", contempt)
+}
+
+// MinElo and MaxElo bound the ratings accepted by SetEloLimit if the engine did not advertise
+// the range of 'UCI_Elo', the range of current Stockfish versions
+var (
+	MinElo = 1320
+	MaxElo = 3190
+)
+
+// SetEloLimit caps the playing strength of the engine at a rating of elo by setting
+// 'UCI_LimitStrength' to true and 'UCI_Elo' to elo, i.e. for opponents of beginners. Ratings
+// outside of the range the engine advertises for 'UCI_Elo', or of MinElo and MaxElo if it
+// advertised none, are rejected. The options are recorded in engine.Param, so the limit
+// survives a Restart.
+func (engine *Engine) SetEloLimit(elo int) error {
+	min, max := MinElo, MaxElo
+	if option, ok := engine.option("UCI_Elo"); ok && option.Min < option.Max {
+		min, max = option.Min, option.Max
+	}
+	if elo < min || elo > max {
+		return fmt.Errorf("Invalid Elo %d, expected %d to %d", elo, min, max)
+	}
+	err := engine.SetOption("UCI_LimitStrength", "true")
+	if err != nil {
+		return err
+	}
+	engine.Param["UCI_LimitStrength"] = "true"
+	err = engine.SetOption("UCI_Elo", strconv.Itoa(elo))
+	if err != nil {
+		return err
+	}
+	engine.Param["UCI_Elo"] = strconv.Itoa(elo)
+	return nil
+}
+
+// DisableEloLimit lets the engine play at full strength again, see SetEloLimit
+func (engine *Engine) DisableEloLimit() error {
+	err := engine.SetOption("UCI_LimitStrength", "false")
+	if err != nil {
+		return err
+	}
+	engine.Param["UCI_LimitStrength"] = "false"
+	delete(engine.Param, "UCI_Elo")
+	return nil
+}
+
+// tablebasesRegex matches the number of Syzygy tablebases the engine reports after setting
+// SyzygyPath, i.e. "info string Found 145 WDL and 145 DTZ tablebase files (up to 5-man)." or
+// "info string Found 510 tablebases" of older versions
+var tablebasesRegex = regexp.MustCompile(`^info string Found (\d+) (WDL|tablebases)`)
+
+// SetSyzygyPath sets 'SyzygyPath' to the directories of Syzygy tablebases, separated by ':' (';'
+// on Windows), and verifies that the engine found tables there. An empty path disables the
+// tablebases. The path is recorded in engine.Param, so it survives a Restart.
+func (engine *Engine) SetSyzygyPath(path string) error {
+	if path == "" {
+		path = "<empty>"
+	}
+	found := -1
+	engine.SetOptionNoWait("SyzygyPath", path)
+	err := engine.fenceLines(true, func(line string) {
+		if matches := tablebasesRegex.FindStringSubmatch(line); matches != nil && found < 0 {
+			found, _ = strconv.Atoi(matches[1])
+		}
+	})
+	if err != nil {
+		return err
+	}
+	engine.Param["SyzygyPath"] = path
+	if path == "<empty>" {
+		return nil
+	}
+	if found < 0 {
+		return fmt.Errorf("Could not verify tablebases in %s: the engine did not report any", path)
+	}
+	if found == 0 {
+		return fmt.Errorf("No tablebases found in %s", path)
+	}
+	return nil
+}
+
+// SetSyzygyProbeDepth sets 'SyzygyProbeDepth', the minimum remaining search depth (1 to 100)
+// at which the tablebases are probed
+func (engine *Engine) SetSyzygyProbeDepth(depth int) error {
+	if depth < 1 || depth > 100 {
+		return fmt.Errorf("Invalid Syzygy probe depth %d", depth)
+	}
+	err := engine.SetOption("SyzygyProbeDepth", strconv.Itoa(depth))
+	if err != nil {
+		return err
+	}
+	engine.Param["SyzygyProbeDepth"] = strconv.Itoa(depth)
+	return nil
+}
+
+// SetSyzygyProbeLimit sets 'SyzygyProbeLimit', the maximum number of pieces (0 to 7) of the
+// positions probed in the tablebases
+func (engine *Engine) SetSyzygyProbeLimit(pieces int) error {
+	if pieces < 0 || pieces > 7 {
+		return fmt.Errorf("Invalid Syzygy probe limit %d", pieces)
+	}
+	err := engine.SetOption("SyzygyProbeLimit", strconv.Itoa(pieces))
+	if err != nil {
+		return err
+	}
+	engine.Param["SyzygyProbeLimit"] = strconv.Itoa(pieces)
+	return nil
+}
+
+// Mode is a kind of engine usage with its own bundle of options, see SetMode
+type Mode string
+
+// Modes of engine usage
+const (
+	ModePlay     Mode = "play"
+	ModeAnalysis Mode = "analysis"
+)
+
+// AnalysisMultiPV is the number of principal variations searched in ModeAnalysis
+var AnalysisMultiPV = 3
+
+// SetMode applies the bundle of options for competitive play or analysis:
+//
+//	ModePlay:     UCI_AnalyseMode false, Contempt from engine.Param, MultiPV 1, Ponder as engine.Ponder
+//	ModeAnalysis: UCI_AnalyseMode true, Contempt 0, MultiPV AnalysisMultiPV, Ponder false
+//
+// Options the engine does not advertise are skipped. The options except Contempt are recorded
+// in engine.Param, so the mode survives a Restart.
+func (engine *Engine) SetMode(mode Mode) error {
+	var options map[string]string
+	switch mode {
+	case ModePlay:
+		contempt, ok := engine.Param["Contempt"]
+		if !ok {
+			option, _ := engine.option("Contempt")
+			contempt = option.Default
+		}
+		options = map[string]string{
+			"UCI_AnalyseMode": "false",
+			"Contempt":        contempt,
+			"MultiPV":         "1",
+			"Ponder":          strconv.FormatBool(engine.Ponder),
+		}
+	case ModeAnalysis:
+		options = map[string]string{
+			"UCI_AnalyseMode": "true",
+			"Contempt":        "0",
+			"MultiPV":         strconv.Itoa(AnalysisMultiPV),
+			"Ponder":          "false",
+		}
+	default:
+		return fmt.Errorf("Unknown mode: %s", mode)
+	}
+
+	for _, name := range []string{"UCI_AnalyseMode", "Contempt", "MultiPV", "Ponder"} {
+		if !engine.HasOption(name) {
+			continue
+		}
+		err := engine.SetOption(name, options[name])
+		if err != nil {
+			return err
+		}
+		if name != "Contempt" {
+			engine.Param[name] = options[name]
+		}
+	}
+	return nil
+}
+
+// IsReady is used to synchronize the golang engine object with the back-end engine. Sends 'isready' and waits for 'readyok.'
+// Returns a *DesyncError if output of a search or the uci handshake arrives before 'readyok', and
+// ErrNotReady if 'readyok' does not arrive within ReadyTimeout, see IsReadyTimeout.
+func (engine *Engine) IsReady() error {
+	return engine.fence(true)
+}
+
+// ReadyTimeout is how long IsReady and the commands synchronizing with 'isready' wait for
+// 'readyok'. 0 waits forever.
+var ReadyTimeout = 30 * time.Second
+
+// ErrNotReady matches the error of IsReadyTimeout with errors.Is
+var ErrNotReady = errors.New("Engine not ready")
+
+// IsReadyTimeout is like IsReady but gives up waiting for 'readyok' after d, i.e. if the engine
+// process hangs, and returns an error matching ErrNotReady. A late 'readyok', and the output
+// preceding it, is skipped by the next command waiting for 'readyok', so it is not taken for
+// the answer to that command.
+func (engine *Engine) IsReadyTimeout(d time.Duration) error {
+	return engine.fenceWithin(d, true, nil)
+}
+
+// fence sends 'isready' and reads the engine output up to 'readyok'. If strict is set, search or
+// handshake output is reported as a *DesyncError; it is expected while a search is running.
+func (engine *Engine) fence(strict bool) error {
+	return engine.fenceLines(strict, nil)
+}
+
+// fenceLines is like fence and calls onLine, if not nil, for every line read before 'readyok'
+func (engine *Engine) fenceLines(strict bool, onLine func(line string)) error {
+	return engine.fenceWithin(ReadyTimeout, strict, onLine)
+}
+
+// fenceWithin is like fenceLines but waits at most d for 'readyok', forever if d is 0. The
+// 'readyok' of fences which timed out before are read first, together with the lines before
+// them, which answer earlier commands.
+func (engine *Engine) fenceWithin(d time.Duration, strict bool, onLine func(line string)) error {
+	ctx := context.Background()
+	if d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	engine.Put("isready")
+	var rejected error
+	for {
+		line, err := engine.readLine(ctx)
+		if err == context.DeadlineExceeded {
+			engine.lateReady++
+			return fmt.Errorf("%w after %s", ErrNotReady, d)
+		}
+		if err != nil {
+			return err
+		}
+		if engine.lateReady > 0 {
+			if line == "readyok" {
+				engine.lateReady--
+			}
+			continue
+		}
+		if onLine != nil && line != "readyok" {
+			onLine(line)
+		}
+		// keep reading up to 'readyok', so the next command is not answered by this one
+		if rejected == nil && (strings.Contains(line, "No such option:") || strings.Contains(line, "Unknown command:")) {
+			rejected = &CommandError{Line: line}
+		}
+		if rejected == nil && strict && isSearchOrHandshakeLine(line) {
+			rejected = &DesyncError{Expected: "readyok", Line: line}
+		}
+		if line == "readyok" {
+			return rejected
+		}
+	}
+}
+
+// ErrProtocolDesync matches a *DesyncError with errors.Is
+var ErrProtocolDesync = errors.New("Engine output out of sync")
+
+// DesyncError is returned when the engine sends a line where another reply is expected, i.e.
+// info lines of a search which were not read before the next command. The commands and their
+// replies are out of sync, following results cannot be trusted.
+type DesyncError struct {
+	Expected string
+	Line     string
+}
+
+func (err *DesyncError) Error() string {
+	return fmt.Sprintf("%s: expected %s, received %s", ErrProtocolDesync.Error(), err.Expected, err.Line)
+}
+
+// Is makes errors.Is(err, ErrProtocolDesync) hold
+func (err *DesyncError) Is(target error) bool {
+	return target == ErrProtocolDesync
+}
+
+// isSearchOrHandshakeLine reports whether the line is a reply to 'go' or 'uci'
+func isSearchOrHandshakeLine(line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "bestmove", "uciok", "id", "option":
+		return true
+	case "info":
+		return len(fields) > 1 && fields[1] != "string"
+	}
+	return false
+}
+
+// CommandError is returned by IsReady if the engine rejected a previous command, i.e. with
+// "No such option: Contempt"
+type CommandError struct {
+	Line string
+}
+
+func (err *CommandError) Error() string {
+	return err.Line
+}
+
+// NewGame calls 'ucinewgame' - this should be run before a new game
+func (engine *Engine) NewGame() error {
+	engine.Put("ucinewgame")
+	return engine.IsReady()
+}
+
+// SetPosition sets start position to list of moves (i.e. ['e2e4', 'e7e5', ...]).  Moves must be in full algebraic notation.
+//
+// If there are more than MaxPositionMoves moves, the earlier moves are applied locally and the
+// position is sent as 'position fen ... moves ...' with the last MaxPositionMoves moves, which
+// the engine still needs to detect repetitions.
+func (engine *Engine) SetPosition(moves []string) error {
+	if len(moves) > MaxPositionMoves {
+		return engine.SetFENPositionWithMoves(StartFEN, moves)
+	}
+	engine.putPosition(fmt.Sprintf("position startpos moves %s", strings.Join(moves, " ")))
+	engine.setSideToMove(StartFEN, len(moves))
+	return engine.IsReady()
+}
+
+// SetFENPosition sets start position in FEN notation. Input is a FEN string i.e. "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+func (engine *Engine) SetFENPosition(fen string) error {
+	engine.putPosition(fmt.Sprintf("position fen %s", fen))
+	engine.setSideToMove(fen, 0)
+	return engine.IsReady()
+}
+
+// SetFENPositionWithMoves sets the position reached by playing the list of moves (i.e. ['e2e4', 'e7e5', ...])
+// from a start position in FEN notation. Moves must be in full algebraic notation. Long move
+// lists are shortened as described for SetPosition.
+func (engine *Engine) SetFENPositionWithMoves(fen string, moves []string) error {
+	if len(moves) > MaxPositionMoves {
+		split := len(moves) - MaxPositionMoves
+		var err error
+		fen, err = PVFEN(fen, strings.Join(moves[:split], " "))
+		if err != nil {
+			return err
+		}
+		moves = moves[split:]
+	}
+	engine.putPosition(fmt.Sprintf("position fen %s moves %s", fen, strings.Join(moves, " ")))
+	engine.setSideToMove(fen, len(moves))
+	return engine.IsReady()
+}
+
+// putPosition sends the 'position' command and remembers it for ResendPosition
+func (engine *Engine) putPosition(command string) {
+	engine.position, engine.flipped = command, false
+	engine.Put(command)
+}
+
+// currentPosition returns the start position in FEN notation and the moves of the last
+// 'position' command
+func (engine *Engine) currentPosition() (string, []string, error) {
+	if engine.position == "" {
+		return "", nil, errors.New("No position set")
+	}
+	fen, moves := engine.position, ""
+	if i := strings.Index(fen, " moves"); i >= 0 {
+		fen, moves = fen[:i], fen[i+len(" moves"):]
+	}
+	fen = strings.TrimPrefix(fen, "position ")
+	if fen == "startpos" {
+		fen = StartFEN
+	} else {
+		fen = strings.TrimPrefix(fen, "fen ")
+	}
+	return fen, strings.Fields(moves), nil
+}
+
+// currentBoard returns the board after playing the moves from the position in FEN notation
+func currentBoard(fen string, moves []string) (*Board, error) {
+	board, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range moves {
+		err = board.Apply(m)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return board, nil
+}
+
+// ParseSANMove validates a move in standard algebraic notation (i.e. 'Nbd2') against the
+// current position and returns it in full algebraic notation (i.e. 'b1d2'). Malformed,
+// ambiguous and illegal moves and moves of pieces not on the board are reported as errors.
+func (engine *Engine) ParseSANMove(san string) (string, error) {
+	if engine.flipped {
+		return "", errors.New("Could not parse moves for a flipped position")
+	}
+	fen, moves, err := engine.currentPosition()
+	if err != nil {
+		return "", err
+	}
+	board, err := currentBoard(fen, moves)
+	if err != nil {
+		return "", err
+	}
+	return board.SANToUCI(strings.TrimSpace(san))
+}
+
+// putGo sends a 'go' command, preceded by the current position if engine.ResendPosition is set
+func (engine *Engine) putGo(command string) error {
+	if engine.ResendPosition && engine.position != "" {
+		engine.Put(engine.position)
+		if engine.flipped {
+			engine.Put("flip")
+		}
+		err := engine.IsReady()
+		if err != nil {
+			return err
+		}
+	}
+	engine.Put(command)
+	return nil
+}
+
+// setSideToMove tracks the side to move after playing a number of moves from the position in
+// FEN notation
+func (engine *Engine) setSideToMove(fen string, moves int) {
+	fields := strings.Fields(fen)
+	engine.sideToMove = ""
+	if len(fields) < 2 || (fields[1] != "w" && fields[1] != "b") {
+		return
+	}
+	white := fields[1] == "w"
+	if moves%2 == 1 {
+		white = !white
+	}
+	engine.sideToMove = ColorBlack
+	if white {
+		engine.sideToMove = ColorWhite
+	}
+}
+
+// SetPositionPGN sets the position reached at the end of the main line of a PGN game.
+// Moves must be in standard algebraic notation; a FEN tag sets the starting position.
+func (engine *Engine) SetPositionPGN(pgn string) error {
+	fen, moves, err := ParsePGN(pgn)
+	if err != nil {
+		return err
+	}
+	if fen == StartFEN {
+		return engine.SetPosition(moves)
+	}
+	return engine.SetFENPositionWithMoves(fen, moves)
+}
+
+// Flip mirrors the current position, swapping the colors of all pieces and the side to move
+func (engine *Engine) Flip() error {
+	engine.Put("flip")
+	engine.flipped = !engine.flipped
+	// flip swaps the colors, so the other side is to move
+	switch engine.sideToMove {
+	case ColorWhite:
+		engine.sideToMove = ColorBlack
+	case ColorBlack:
+		engine.sideToMove = ColorWhite
+	}
+	return engine.IsReady()
+}
+
+// BestMoveBothSides gets the proposed best move for the current position and for the same
+// position flipped, so the evaluations of both perspectives can be compared. The position is
+// flipped back before returning.
+func (engine *Engine) BestMoveBothSides() (*BestMove, *BestMove, error) {
+	bestMove, err := engine.BestMove()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = engine.Flip()
+	if err != nil {
+		return nil, nil, err
+	}
+	flipped, err := engine.BestMove()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = engine.Flip()
+	if err != nil {
+		return nil, nil, err
+	}
+	return bestMove, flipped, nil
+}
+
+// ScoreBothSides searches the current position and the same position flipped to the given
+// depth and returns both scores, see GetScore. Each score is from the point of view of the side
+// to move, so a symmetric evaluation gives equal scores; see ScoreAsymmetry. Contempt makes the
+// engine score draws in favour of the side it plays, which depends on the side to move, so it
+// should be 0 (see SetAnalyseMode) unless its effect is studied. The position is flipped back
+// before returning.
+func (engine *Engine) ScoreBothSides(depth int) (Score, Score, error) {
+	score, err := engine.GetScore(depth)
+	if err != nil {
+		return Score{}, Score{}, err
+	}
+	err = engine.Flip()
+	if err != nil {
+		return Score{}, Score{}, err
+	}
+	flipped, err := engine.GetScore(depth)
+	if err != nil {
+		return Score{}, Score{}, err
+	}
+	err = engine.Flip()
+	if err != nil {
+		return Score{}, Score{}, err
+	}
+	return score, flipped, nil
+}
+
+// ScoreAsymmetry returns how many centipawns the flipped score of ScoreBothSides is above the
+// score of the position itself, 0 for a symmetric evaluation. Mates count as mateCentipawns
+// less the number of moves to mate.
+func ScoreAsymmetry(score Score, flipped Score) int {
+	return candidateCentipawns(flipped) - candidateCentipawns(score)
+}
+
+// InCheck reports whether the side to move is in check in the current position
+func (engine *Engine) InCheck() (bool, error) {
+	lines, err := engine.display()
+	if err != nil {
+		return false, err
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "Checkers:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Checkers:")) != "", nil
+		}
+	}
+	return false, errors.New("Could not find checkers in engine output")
+}
+
+// GetFEN returns the current position of the engine in FEN notation, read from the output of
+// 'd'. The output is not terminated by readyok, so it is read up to the Fen line and followed
+// by 'isready', which also skips the remaining lines.
+func (engine *Engine) GetFEN() (string, error) {
+	engine.Put("d")
+	fen := ""
+	for fen == "" {
+		line, err := engine.readLine(context.Background())
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(line, "Fen:") {
+			fen = strings.TrimSpace(strings.TrimPrefix(line, "Fen:"))
+		} else if strings.HasPrefix(line, "Checkers:") || strings.Contains(line, "Unknown command:") {
+			// the description of the position is over, or the engine does not know 'd'
+			break
+		}
+	}
+
+	err := engine.IsReady()
+	if err != nil {
+		return "", err
+	}
+	if fen == "" {
+		return "", errors.New("Could not find FEN in engine output")
+	}
+	return fen, nil
+}
+
+// display sends 'd' and returns the engine's description of the current position (board,
+// Fen, Key and Checkers lines). The output is not terminated by readyok, so it is read up to
+// the Checkers line and followed by 'isready'.
+func (engine *Engine) display() ([]string, error) {
+	var lines []string
+
+	engine.Put("d")
+	for {
+		line, err := engine.readLine(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+		if strings.HasPrefix(line, "Checkers:") {
+			break
+		}
+	}
+
+	err := engine.IsReady()
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// LegalMoves returns the legal moves of the side to move in the current position in full
+// algebraic notation, as listed by the engine for 'go perft 1'
+func (engine *Engine) LegalMoves() ([]string, error) {
+	moves, _, err := engine.perft1()
+	return moves, err
+}
+
+// MoveCount returns the number of legal moves of the side to move in the current position (the
+// mobility), the node count of 'go perft 1'
+func (engine *Engine) MoveCount() (int, error) {
+	_, nodes, err := engine.perft1()
+	return nodes, err
+}
+
+// perft1 sends 'go perft 1' and returns the moves and the total number of nodes listed
+func (engine *Engine) perft1() ([]string, int, error) {
+	var moves []string
+	nodes := 0
+
+	err := engine.putGo("go perft 1")
+	if err != nil {
+		return nil, 0, err
+	}
+	for {
+		line, err := engine.readLine(context.Background())
+		if err != nil {
+			return nil, 0, err
+		}
+		if strings.HasPrefix(line, "Nodes searched:") {
+			nodes, err = strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Nodes searched:")))
+			if err != nil {
+				return nil, 0, fmt.Errorf("Could not parse perft result: %s", line)
+			}
+			break
+		}
+		// i.e. "e2e4: 1"
+		if i := strings.Index(line, ":"); i > 0 && isUCIMove(line[:i]) {
+			moves = append(moves, line[:i])
+		}
+	}
+
+	err = engine.IsReady()
+	if err != nil {
+		return nil, 0, err
+	}
+	return moves, nodes, nil
+}
+
+// IsLegalMove reports whether the move in full algebraic notation (i.e. 'e2e4') is legal in the
+// current position. Engines silently ignore illegal moves of the 'position' command, so moves
+// entered by a user should be checked before they are played.
+func (engine *Engine) IsLegalMove(move string) (bool, error) {
+	moves, err := engine.LegalMoves()
+	if err != nil {
+		return false, err
+	}
+	for _, legal := range moves {
+		if legal == move {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Eval returns the static evaluation of the current position in pawns from white's point of view
+func (engine *Engine) Eval() (float64, error) {
+	evaluation, err := engine.EvalBreakdown()
+	if err != nil {
+		return 0, err
+	}
+	return evaluation.Final, nil
+}
+
+// EvalBreakdown returns the static evaluation of the current position together with the
+// contribution of each evaluation term (Material, Mobility, King safety, ...)
+func (engine *Engine) EvalBreakdown() (*Evaluation, error) {
+	var lines []string
+
+	engine.Put("eval")
+	for {
+		line, err := engine.readLine(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+		if strings.HasPrefix(line, "Final evaluation") {
+			break
+		}
+	}
+
+	err := engine.IsReady()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseEvaluation(lines)
+}
+
+// Go starts calculating on the current position
+func (engine *Engine) Go() error {
+	err := engine.putGo(fmt.Sprintf("go depth %s", strconv.Itoa(engine.Depth)))
+	if err != nil {
+		return err
+	}
+	return engine.fence(false)
+}
+
+// GoMovetime starts calculating on the current position for ms milliseconds. Like GoInfinite
+// it returns right away, the best move should be read with Stop before sending any other
+// command, as the engine may send it before answering 'isready'.
+func (engine *Engine) GoMovetime(ms int) error {
+	if ms < 1 {
+		return fmt.Errorf("Invalid movetime %d", ms)
+	}
+	return engine.putGo(fmt.Sprintf("go movetime %d", ms))
+}
+
+// BestMoveMovetime gets the proposed best move for current position searching for ms
+// milliseconds instead of to engine.Depth, i.e. for bots which must move within a fixed time
+// regardless of the position
+func (engine *Engine) BestMoveMovetime(ms int) (*BestMove, error) {
+	if ms < 1 {
+		return nil, fmt.Errorf("Invalid movetime %d", ms)
+	}
+	return engine.search(context.Background(), fmt.Sprintf("go movetime %d", ms), nil)
+}
+
+// GoNodes starts calculating on the current position until nodes positions are searched and
+// returns right away, like GoMovetime
+func (engine *Engine) GoNodes(nodes int) error {
+	if nodes < 1 {
+		return fmt.Errorf("Invalid number of nodes %d", nodes)
+	}
+	return engine.putGo(fmt.Sprintf("go nodes %d", nodes))
+}
+
+// BestMoveNodes gets the proposed best move for current position searching nodes positions
+// instead of to engine.Depth. Unlike depth and time limits, a node budget gives the same search
+// on every machine (with Threads set to 1), i.e. for benchmarks and engine comparisons.
+func (engine *Engine) BestMoveNodes(nodes int) (*BestMove, error) {
+	if nodes < 1 {
+		return nil, fmt.Errorf("Invalid number of nodes %d", nodes)
+	}
+	return engine.search(context.Background(), fmt.Sprintf("go nodes %d", nodes), nil)
+}
+
+// ErrNoMate is returned by GoMate if the engine found no mate within the limit
+var ErrNoMate = errors.New("No mate found")
+
+// GoMate searches the current position for a mate in n moves (of the side to move) and returns
+// the first move of the mate, with Info.Score.Eval "mate". If the engine finds no mate in n, its
+// best move is returned together with ErrNoMate, so solved and unsolved positions can be told
+// apart with errors.Is.
+func (engine *Engine) GoMate(n int) (*BestMove, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("Invalid number of moves %d", n)
+	}
+	bestMove, err := engine.search(context.Background(), fmt.Sprintf("go mate %d", n), nil)
+	if err != nil {
+		return nil, err
+	}
+	info := bestMove.Info
+	if info == nil || info.Score.Eval != "mate" || info.Score.Value < 1 || info.Score.Value > n {
+		return bestMove, ErrNoMate
+	}
+	return bestMove, nil
+}
+
+// GoInfinite starts calculating on the current position without any limit. The search does
+// not end on its own: the caller must call Stop to end it and retrieve the best move before
+// sending any other command. GoInfinite returns right away, it does not wait for 'readyok'.
+func (engine *Engine) GoInfinite() error {
+	return engine.putGo("go infinite")
+}
+
+// Stop ends the running search, i.e. one started by GoInfinite, and returns its best move
+func (engine *Engine) Stop() (*BestMove, error) {
+	engine.Put("stop")
+	return engine.readBestMove(context.Background(), nil)
+}
+
+// BestMove gets the proposed best move for current position.
+func (engine *Engine) BestMove() (*BestMove, error) {
+	return engine.BestMoveContext(context.Background())
+}
+
+// BestMoveContext gets the proposed best move for current position. If ctx is done before
+// the search completes, the engine is sent 'stop' and ctx.Err() is returned once the engine
+// has answered with its bestmove, so the engine is ready for the next command.
+func (engine *Engine) BestMoveContext(ctx context.Context) (*BestMove, error) {
+	return engine.search(ctx, fmt.Sprintf("go depth %s", strconv.Itoa(engine.Depth)), nil)
+}
+
+// BestMoveSearchMoves gets the proposed best move for current position like BestMove, but only
+// considers the given root moves in full algebraic notation (i.e. 'e2e4'), i.e. for the best
+// of a few candidate moves. Malformed moves are reported as an error before searching.
+func (engine *Engine) BestMoveSearchMoves(moves []string) (*BestMove, error) {
+	if len(moves) == 0 {
+		return nil, errors.New("No moves to search")
+	}
+	var malformed []string
+	for _, m := range moves {
+		if !isUCIMove(m) {
+			malformed = append(malformed, m)
+		}
+	}
+	if len(malformed) > 0 {
+		return nil, fmt.Errorf("Could not parse search moves: %s", strings.Join(malformed, ", "))
+	}
+	return engine.search(context.Background(), fmt.Sprintf("go depth %d searchmoves %s", engine.Depth, strings.Join(moves, " ")), nil)
+}
+
+// SearchLimits bounds a search: it ends as soon as any of the limits is reached. Zero values
+// are unset.
+type SearchLimits struct {
+	Depth    int
+	MoveTime time.Duration
+	Nodes    int64
+}
+
+// command returns the 'go' command for the limits, searching to depth if no limit is set
+func (limits SearchLimits) command(depth int) string {
+	command := "go"
+	if limits.Depth > 0 {
+		command += fmt.Sprintf(" depth %d", limits.Depth)
+	}
+	if limits.MoveTime > 0 {
+		command += fmt.Sprintf(" movetime %d", limits.MoveTime.Milliseconds())
+	}
+	if limits.Nodes > 0 {
+		command += fmt.Sprintf(" nodes %d", limits.Nodes)
+	}
+	if command == "go" {
+		command += fmt.Sprintf(" depth %d", depth)
+	}
+	return command
+}
+
+// BestMoveLimits gets the proposed best move for current position searching within the limits
+// instead of to engine.Depth, i.e. for a fixed time per move. If no limit is set, the search
+// goes to engine.Depth. See BestMoveContext for ctx.
+func (engine *Engine) BestMoveLimits(ctx context.Context, limits SearchLimits) (*BestMove, error) {
+	return engine.search(ctx, limits.command(engine.Depth), nil)
+}
+
+// BestMoveWithProgress gets the proposed best move for current position like BestMove and calls
+// onInfo for every info line of the search, i.e. to report the search deepening. This includes
+// reports of the move currently searched, see Info.IsCurrMove. onInfo is called from the loop
+// reading the engine output and should return quickly.
+func (engine *Engine) BestMoveWithProgress(onInfo func(*Info)) (*BestMove, error) {
+	return engine.search(context.Background(), fmt.Sprintf("go depth %s", strconv.Itoa(engine.Depth)), func(info *Info) bool {
+		onInfo(info)
+		return false
+	})
+}
+
+// BestMoveLimitsWithProgress is like BestMoveLimits and calls onInfo for every info line of the
+// search, see BestMoveWithProgress
+func (engine *Engine) BestMoveLimitsWithProgress(ctx context.Context, limits SearchLimits, onInfo func(*Info)) (*BestMove, error) {
+	return engine.search(ctx, limits.command(engine.Depth), func(info *Info) bool {
+		onInfo(info)
+		return false
+	})
+}
+
+// BestMoveWithDepths gets the proposed best move for current position like BestMove and also
+// returns the last info line (of the first principal variation) reported for each depth, in
+// ascending depth order, i.e. to plot how the evaluation changed as the engine searched deeper
+func (engine *Engine) BestMoveWithDepths() (*BestMove, []*Info, error) {
+	var depths []*Info
+	bestMove, err := engine.search(context.Background(), fmt.Sprintf("go depth %s", strconv.Itoa(engine.Depth)), func(info *Info) bool {
+		if info.Multipv > 1 || info.Depth == 0 || info.IsCurrMove() {
+			return false
+		}
+		if len(depths) > 0 && depths[len(depths)-1].Depth == info.Depth {
+			depths[len(depths)-1] = info
+		} else {
+			depths = append(depths, info)
+		}
+		return false
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.SliceStable(depths, func(i, j int) bool {
+		return depths[i].Depth < depths[j].Depth
+	})
+	return bestMove, depths, nil
+}
+
+// BestMoveVerbose gets the proposed best move for current position like BestMove and also
+// returns all info lines of the search in the order received, i.e. for logging. Info strings
+// are left out. A deep search reports thousands of lines, all kept in memory; see
+// BestMoveWithProgress to process them as they arrive instead.
+func (engine *Engine) BestMoveVerbose() (*BestMove, []*Info, error) {
+	var infos []*Info
+	bestMove, err := engine.search(context.Background(), fmt.Sprintf("go depth %s", strconv.Itoa(engine.Depth)), func(info *Info) bool {
+		infos = append(infos, info)
+		return false
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return bestMove, infos, nil
+}
+
+// Analyze searches the current position to engine.Depth and returns the info lines of the
+// deepest completed depth, one per principal variation sorted by Multipv, i.e. the top moves
+// with their scores if the MultiPV option is set. A depth is completed once it reported as many
+// principal variations as any depth of the search; fail high or low lines are replaced by the
+// final line of the same principal variation.
+func (engine *Engine) Analyze() ([]*Info, error) {
+	depths := map[int]map[int]*Info{}
+	_, err := engine.search(context.Background(), fmt.Sprintf("go depth %s", strconv.Itoa(engine.Depth)), func(info *Info) bool {
+		if info.Depth == 0 || info.IsCurrMove() {
+			return false
+		}
+		if depths[info.Depth] == nil {
+			depths[info.Depth] = map[int]*Info{}
+		}
+		depths[info.Depth][info.Multipv] = info
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lines := 0
+	for _, pvs := range depths {
+		if len(pvs) > lines {
+			lines = len(pvs)
+		}
+	}
+	deepest := 0
+	for depth, pvs := range depths {
+		if len(pvs) == lines && depth > deepest {
+			deepest = depth
+		}
+	}
+
+	var infos []*Info
+	for _, info := range depths[deepest] {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Multipv < infos[j].Multipv
+	})
+	return infos, nil
+}
+
+// AnalyzeStable searches the position in FEN notation up to maxDepth and stops once the best
+// move of the first principal variation has stayed the same for stableDepths completed depths
+// in a row, i.e. to label positions by moves that do not flip at the next depth. Returns the
+// stable move and the depth from which on it was the best move. If the best move is not stable
+// by maxDepth or the search is cut short by engine.MaxSearchTime, the engine's best move is
+// returned with depth 0.
+func (engine *Engine) AnalyzeStable(fen string, maxDepth int, stableDepths int) (string, int, error) {
+	if maxDepth < 1 {
+		return "", 0, fmt.Errorf("Invalid depth %d", maxDepth)
+	}
+	if stableDepths < 1 {
+		return "", 0, fmt.Errorf("Invalid number of stable depths %d", stableDepths)
+	}
+	err := engine.SetFENPosition(fen)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var last *Info
+	move, since, stable := "", 0, false
+	// complete takes note of the best move of a completed depth
+	complete := func(info *Info) {
+		pv := strings.Fields(info.Pv)
+		if len(pv) == 0 {
+			return
+		}
+		if pv[0] != move {
+			move, since = pv[0], info.Depth
+		}
+		stable = info.Depth-since+1 >= stableDepths
+	}
+
+	bestMove, err := engine.search(context.Background(), fmt.Sprintf("go depth %d", maxDepth), func(info *Info) bool {
+		if info.Multipv > 1 || info.Depth == 0 || info.IsCurrMove() || stable {
+			return false
+		}
+		if last != nil && info.Depth > last.Depth {
+			complete(last)
+		}
+		last = info
+		return stable
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	if !stable && last != nil && last.Depth == maxDepth {
+		complete(last)
+	}
+	if !stable {
+		return bestMove.Move, 0, nil
+	}
+	return move, since, nil
+}
+
+// BestMoveUntil gets the proposed best move for current position, but stops the search early
+// once an info line reports more than maxNodes nodes (if maxNodes is positive) or stop returns
+// true for it (if stop is not nil). Both conditions are evaluated for every info line, so how
+// soon the search stops depends on how often the engine reports. The returned BestMove holds
+// the last Info seen.
+func (engine *Engine) BestMoveUntil(stop func(*Info) bool, maxNodes int64) (*BestMove, error) {
+	return engine.search(context.Background(), fmt.Sprintf("go depth %s", strconv.Itoa(engine.Depth)), func(info *Info) bool {
+		if maxNodes > 0 && info.Nodes > maxNodes {
+			return true
+		}
+		return stop != nil && stop(info)
+	})
+}
+
+// AnalyzeAfter searches the position reached by playing the moves (i.e. ['e2e4', 'e7e5', ...])
+// on top of the current position to the given depth, i.e. to evaluate a hypothetical line.
+// The moves are checked to be legal first. The current position is set again afterwards, also
+// if the search fails, so the position of the engine does not change.
+func (engine *Engine) AnalyzeAfter(moves []string, depth int) (*BestMove, error) {
+	if depth < 1 {
+		return nil, fmt.Errorf("Invalid depth %d", depth)
+	}
+	if engine.flipped {
+		return nil, errors.New("Could not analyze moves after a flipped position")
+	}
+	fen, played, err := engine.currentPosition()
+	if err != nil {
+		return nil, err
+	}
+	board, err := currentBoard(fen, played)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range moves {
+		err = board.Apply(m)
+		if err != nil {
+			return nil, fmt.Errorf("Could not play hypothetical move %s: %s", m, err.Error())
+		}
+	}
+
+	position, sideToMove := engine.position, engine.sideToMove
+	bestMove, err := engine.analyzeAfter(fen, append(append([]string{}, played...), moves...), depth)
+
+	engine.putPosition(position)
+	engine.sideToMove = sideToMove
+	restoreErr := engine.IsReady()
+	if err != nil {
+		return nil, err
+	}
+	return bestMove, restoreErr
+}
+
+// analyzeAfter sets the position and searches it to depth, see AnalyzeAfter
+func (engine *Engine) analyzeAfter(fen string, moves []string, depth int) (*BestMove, error) {
+	err := engine.SetFENPositionWithMoves(fen, moves)
+	if err != nil {
+		return nil, err
+	}
+	return engine.search(context.Background(), fmt.Sprintf("go depth %d", depth), nil)
+}
+
+// GetScore searches the current position to the given depth and returns the score of the
+// final info line, discarding the best move. The score is from the point of view of the side
+// to move.
+func (engine *Engine) GetScore(depth int) (Score, error) {
+	if depth < 1 {
+		return Score{}, fmt.Errorf("Invalid depth %d", depth)
+	}
+	bestMove, err := engine.search(context.Background(), fmt.Sprintf("go depth %d", depth), nil)
+	if err != nil {
+		return Score{}, err
+	}
+	if bestMove.Info == nil {
+		return Score{}, fmt.Errorf("Engine reported no score for bestmove %s", bestMove.Move)
+	}
+	return bestMove.Info.Score, nil
+}
+
+// GoPonder starts pondering on the current position, which should end with the expected reply
+// of the opponent (BestMove.Ponder). It does not wait for the search: the engine keeps thinking
+// until PonderHit is called because the opponent played the expected move, or StopPonder
+// because the opponent played another move.
+func (engine *Engine) GoPonder() error {
+	err := engine.putGo(fmt.Sprintf("go ponder depth %s", strconv.Itoa(engine.Depth)))
+	if err != nil {
+		return err
+	}
+	return engine.fence(false)
+}
+
+// StartPondering plays the best move and the expected reply (bestMove.Ponder) on the current
+// position and starts pondering on the resulting position, see GoPonder. The caller then waits
+// for the opponent's move: PonderHit if it is the expected reply, StopPonder otherwise.
+func (engine *Engine) StartPondering(bestMove *BestMove) error {
+	if bestMove.Ponder == "" || bestMove.Ponder == "(none)" {
+		return fmt.Errorf("No move to ponder on after %s", bestMove.Move)
+	}
+	if engine.flipped {
+		return errors.New("Could not ponder on a flipped position")
+	}
+	fen, moves, err := engine.currentPosition()
+	if err != nil {
+		return err
+	}
+	err = engine.SetFENPositionWithMoves(fen, append(append([]string{}, moves...), bestMove.Move, bestMove.Ponder))
+	if err != nil {
+		return err
+	}
+	return engine.GoPonder()
+}
+
+// PonderHit tells the pondering engine that the opponent played the expected move, turning
+// the ponder search into a normal search, and returns its best move
+func (engine *Engine) PonderHit() (*BestMove, error) {
+	return engine.PonderHitContext(context.Background())
+}
+
+// PonderHitContext is like PonderHit but stops the search once ctx is done, see BestMoveContext
+func (engine *Engine) PonderHitContext(ctx context.Context) (*BestMove, error) {
+	engine.Put("ponderhit")
+	return engine.readBestMove(ctx, nil)
+}
+
+// StopPonder stops the pondering engine because the opponent did not play the expected move
+func (engine *Engine) StopPonder() error {
+	engine.Put("stop")
+	_, err := engine.readBestMove(context.Background(), nil)
+	return err
+}
+
+// search sends a 'go' command and reads the engine output up to the bestmove line. The
+// search is stopped when ctx is done or when onInfo, called for every info line, returns true.
+func (engine *Engine) search(ctx context.Context, command string, onInfo func(*Info) bool) (*BestMove, error) {
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = engine.putGo(command)
+	if err != nil {
+		return nil, err
+	}
+	return engine.readBestMove(ctx, onInfo)
+}
+
+// readBestMove reads the engine output of a running search up to the bestmove line, see search
+func (engine *Engine) readBestMove(ctx context.Context, onInfo func(*Info) bool) (*BestMove, error) {
+	var lastInfo *Info
+	stopSent := false
+
+	var deadline <-chan time.Time
+	if engine.MaxSearchTime > 0 {
+		timer := time.NewTimer(engine.MaxSearchTime)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			engine.Put("stop")
+		case <-deadline:
+			engine.Put("stop")
+		case <-done:
+		}
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	for {
+		line, err := engine.readLine(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		splitText := strings.Fields(line)
+		// a bare "info" carries no information, i.e. it is not allowed to replace the last info line
+		if splitText[0] == "info" && len(splitText) > 1 && !strings.HasPrefix(line, "info string ") {
+			info, err := ParseInfo(line)
+			if err != nil {
+				return nil, err
+			}
+			// progress reports do not replace the last info line, which holds the score and pv
+			if !info.IsCurrMove() {
+				lastInfo = info
+			}
+			if onInfo != nil && !stopSent && onInfo(info) {
+				engine.Put("stop")
+				stopSent = true
+			}
+		}
+		if splitText[0] == "bestmove" {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			bestMove, err := ParseBestMove(line)
+			if err != nil {
+				return nil, err
+			}
+			bestMove.Info = lastInfo
+			bestMove.SideToMove = engine.sideToMove
+			return bestMove, nil
+		}
+	}
+}
+
+// ParseInfo parses stockfish evaluation output
+//
+// Examples of input:
+// "info depth 2 seldepth 3 multipv 1 score cp -656 nodes 43 nps 43000 tbhits 0 time 1 pv g7g6 h3g3 g6f7"
+// "info depth 10 seldepth 12 multipv 1 score mate 5 nodes 2378 nps 1189000 tbhits 0 time 2 pv h3g3 g6f7 g3c7 b5d7 d1d7 f7g6 c7g3 g6h5 e6f4"
+//
+// "info depth 20 currmove e2e4 currmovenumber 1"
+//
+// The line is split into tokens once and walked in a single pass, as ParseInfo is called for
+// every info line of a search. Depth, seldepth, multipv, nodes, nps, tbhits, time, score and
+// pv are required, the first occurrence of each field counts. Lines reporting the move
+// currently searched carry no score and pv and are returned as a partial Info, see
+// Info.IsCurrMove.
+func ParseInfo(line string) (*Info, error) {
+	var err error
+	result := &Info{}
+
+	if strings.HasPrefix(line, "info string ") {
+		return result, nil
+	}
+
+	tokens := strings.Fields(line)
+	// a bare "info", i.e. a truncated line, is parsed as an empty info like an info string
+	if len(tokens) == 1 && tokens[0] == "info" {
+		return result, nil
+	}
+
+	// no legal moves in the position: "info depth 0 score mate 0" (checkmate) or "info depth 0 score cp 0" (stalemate)
+	if len(tokens) == 6 && tokens[0] == "info" && tokens[1] == "depth" && tokens[2] == "0" && tokens[3] == "score" &&
+		(tokens[4] == "cp" || tokens[4] == "mate") && isInteger(tokens[5]) {
+		result.Score.Eval = tokens[4]
+		result.Score.Value, err = strconv.Atoi(tokens[5])
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	pvStart, pvEnd := 0, 0
+	hasPv, hasScore, hasWDL, hasCurrMoveNumber := false, false, false, false
+	var found [len(infoFields)]bool
+	for i := 0; i < len(tokens); i++ {
+		switch field := tokens[i]; field {
+		case "pv":
+			if hasPv || i+1 >= len(tokens) || !isUCIMove(tokens[i+1]) {
+				continue
+			}
+			pvStart = i + 1
+			for i+1 < len(tokens) && isUCIMove(tokens[i+1]) {
+				i++
+			}
+			pvEnd = i + 1
+			hasPv = true
+		case "score":
+			// Example values:
+			// score cp -100        <- engine is behind 100 centipawns
+			// score mate 3         <- engine has big lead or checkmated opponent
+			// score cp 34 lowerbound <- the score is at least 34 centipawns
+			if hasScore || i+2 >= len(tokens) || !isInteger(tokens[i+2]) {
+				continue
+			}
+			result.Score.Eval = tokens[i+1]
+			result.Score.Value, err = strconv.Atoi(tokens[i+2])
+			if err != nil {
+				return nil, err
+			}
+			hasScore = true
+			i += 2
+			// optional, i.e. score cp 34 lowerbound
+			if i+1 < len(tokens) && (tokens[i+1] == "lowerbound" || tokens[i+1] == "upperbound") {
+				result.Score.Bound = strings.TrimSuffix(tokens[i+1], "bound")
+				i++
+			}
+		case "currmove":
+			if result.CurrMove != "" || i+1 >= len(tokens) || !isUCIMove(tokens[i+1]) {
+				continue
+			}
+			result.CurrMove = tokens[i+1]
+			i++
+		case "currmovenumber":
+			if hasCurrMoveNumber || i+1 >= len(tokens) || !isNatural(tokens[i+1]) {
+				continue
+			}
+			result.CurrMoveNumber, err = strconv.Atoi(tokens[i+1])
+			if err != nil {
+				return nil, err
+			}
+			hasCurrMoveNumber = true
+			i++
+		case "wdl":
+			// optional, i.e. wdl 120 850 30
+			if hasWDL || i+3 >= len(tokens) || !isNatural(tokens[i+1]) || !isNatural(tokens[i+2]) || !isNatural(tokens[i+3]) {
+				continue
+			}
+			result.WDL.Win, _ = strconv.Atoi(tokens[i+1])
+			result.WDL.Draw, _ = strconv.Atoi(tokens[i+2])
+			result.WDL.Loss, _ = strconv.Atoi(tokens[i+3])
+			hasWDL = true
+			i += 3
+		default:
+			index := infoFieldIndex(field)
+			if index < 0 || found[index] || i+1 >= len(tokens) || !isNatural(tokens[i+1]) {
+				continue
+			}
+			// node counts of long searches exceed the range of a 32 bit int
+			value, err := strconv.ParseInt(tokens[i+1], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if field == "depth" {
+				result.Depth = int(value)
+			} else if field == "seldepth" {
+				result.Seldepth = int(value)
+			} else if field == "multipv" {
+				result.Multipv = int(value)
+			} else if field == "nodes" {
+				result.Nodes = value
+			} else if field == "nps" {
+				result.Nps = value
+			} else if field == "tbhits" {
+				result.Tbhits = value
+			} else if field == "time" {
+				result.Time = int(value)
+			}
+			found[index] = true
+			i++
+		}
+	}
+
+	if !hasPv && result.CurrMove != "" {
+		return result, nil
+	}
+	if !hasPv {
+		return nil, fmt.Errorf("Could not parse pv: %s", line)
+	}
+	result.Pv = strings.Join(tokens[pvStart:pvEnd], " ")
+	if !hasScore {
+		return nil, fmt.Errorf("Could not parse score: %s", line)
+	}
+	for index, field := range infoFields {
+		if !found[index] {
+			return nil, fmt.Errorf("Could not parse %s: %s", field, line)
+		}
+	}
+
+	return result, nil
+}
+
+// infoFields are the single value fields of an info line required by ParseInfo
+var infoFields = [...]string{"depth", "seldepth", "multipv", "nodes", "nps", "tbhits", "time"}
+
+// infoFieldIndex returns the index of the field in infoFields, or -1
+func infoFieldIndex(field string) int {
+	for index, infoField := range infoFields {
+		if field == infoField {
+			return index
+		}
+	}
+	return -1
+}
+
+// isNatural reports whether the token consists of decimal digits only
+func isNatural(token string) bool {
+	if token == "" {
+		return false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isInteger reports whether the token is a natural number with an optional minus sign
+func isInteger(token string) bool {
+	return isNatural(strings.TrimPrefix(token, "-"))
+}
+
+// isUCIMove reports whether the token is a move in UCI notation, see UCIMoveRegex
+func isUCIMove(token string) bool {
+	if len(token) != 4 && len(token) != 5 {
+		return false
+	}
+	if token[0] < 'a' || token[0] > 'h' || token[2] < 'a' || token[2] > 'h' || !isNatural(token[1:2]) || !isNatural(token[3:4]) {
+		return false
+	}
+	return len(token) == 4 || strings.IndexByte("qrnb", token[4]) >= 0
+}
+
+// finalEvaluationRegex matches the final evaluation line of the 'eval' command
+var finalEvaluationRegex = regexp.MustCompile(`^Final evaluation:?\s+(?P<value>[+-]?\d+(\.\d+)?)`)
+
+// ParseEvaluation parses stockfish output of the 'eval' command
+//
+// Examples of input:
+// "   Mobility |  0.56  0.93 |  0.56  0.93 |  0.00  0.00"
+// "Final evaluation: +0.08 (white side)"
+func ParseEvaluation(lines []string) (*Evaluation, error) {
+	result := &Evaluation{
+		Terms: map[string]EvalTerm{},
+	}
+
+	found := false
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Final evaluation") {
+			if strings.Contains(line, "in check") {
+				return nil, errors.New("No evaluation, side to move is in check")
+			}
+			matches := finalEvaluationRegex.FindStringSubmatch(line)
+			if matches == nil {
+				return nil, fmt.Errorf("Could not parse final evaluation: %s", line)
+			}
+			value, err := strconv.ParseFloat(matches[1], 64)
+			if err != nil {
+				return nil, err
+			}
+			result.Final = value
+			found = true
+			continue
+		}
+
+		columns := strings.Split(line, "|")
+		if len(columns) < 2 {
+			continue
+		}
+		name := strings.TrimSpace(columns[0])
+		if name == "" || name == "Term" || strings.HasPrefix(name, "-") {
+			continue
+		}
+		total := strings.Fields(columns[len(columns)-1])
+		if len(total) != 2 {
+			continue
+		}
+		mg, err := strconv.ParseFloat(total[0], 64)
+		if err != nil {
+			continue
+		}
+		eg, err := strconv.ParseFloat(total[1], 64)
+		if err != nil {
+			continue
+		}
+		result.Terms[name] = EvalTerm{MG: mg, EG: eg}
+	}
+
+	if !found {
+		return nil, errors.New("Could not find final evaluation")
+	}
+	return result, nil
+}
+
+// ParseOption parses an option advertised by the engine during the uci handshake
+//
+// Examples of input:
+// "option name Skill Level type spin default 20 min 0 max 20"
+// "option name Clear Hash type button"
+// "option name Analysis Contempt type combo default Both var Off var White var Black var Both"
+func ParseOption(line string) (*Option, error) {
+	option := &Option{}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "option" || fields[1] != "name" {
+		return nil, fmt.Errorf("Could not parse option: %s", line)
+	}
+
+	var key string
+	var value []string
+	var err error
+	assign := func() {
+		if key == "name" {
+			option.Name = strings.Join(value, " ")
+		} else if key == "type" {
+			option.Type = strings.Join(value, " ")
+		} else if key == "default" {
+			option.Default = strings.Join(value, " ")
+		} else if key == "var" {
+			option.Var = append(option.Var, strings.Join(value, " "))
+		} else if (key == "min" || key == "max") && err == nil {
+			var bound int
+			bound, err = strconv.Atoi(strings.Join(value, " "))
+			if key == "min" {
+				option.Min = bound
+			} else {
+				option.Max = bound
+			}
+		}
+	}
+	for _, field := range fields[1:] {
+		if field == "name" || field == "type" || field == "default" || field == "min" || field == "max" || field == "var" {
+			assign()
+			key = field
+			value = nil
+		} else {
+			value = append(value, field)
+		}
+	}
+	assign()
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse option: %s", line)
+	}
+
+	if option.Name == "" || option.Type == "" {
+		return nil, fmt.Errorf("Could not parse option: %s", line)
+	}
+	return option, nil
+}
+
+// ParseBestMove parses stockfish bestmove output
+//
+// Examples of input:
+// "bestmove d2d4 ponder a7a6"
+func ParseBestMove(line string) (*BestMove, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[0] != "bestmove" {
+		return nil, fmt.Errorf("Could not parse bestmove: %s", line)
+	}
+
+	bestMove := &BestMove{
+		Move: fields[1],
+	}
+	// scan for the keyword, so tokens added by other engines don't matter
+	for i := 2; i < len(fields)-1; i++ {
+		if fields[i] == "ponder" {
+			bestMove.Ponder = fields[i+1]
+			break
+		}
 	}
 
-	return &BestMove{
-		Move:   splitText[1],
-		Ponder: ponder,
-	}, nil
+	return bestMove, nil
 }
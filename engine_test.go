@@ -1,6 +1,117 @@
 package gostockfish
 
-import "testing"
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEngine is a scripted stand-in for a UCI engine process. It answers
+// 'isready' with 'readyok' and everything else with whatever respond returns.
+type fakeEngine struct {
+	mu       sync.Mutex
+	commands []string
+	respond  func(command string) []string
+}
+
+// newFakeEngine returns an Engine whose Stdin and Stdout are connected to a fakeEngine
+func newFakeEngine(respond func(command string) []string) (*Engine, *fakeEngine) {
+	fake := &fakeEngine{respond: respond}
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	output := make(chan string, 4096)
+
+	go func() {
+		scanner := bufio.NewScanner(inReader)
+		for scanner.Scan() {
+			command := scanner.Text()
+			fake.mu.Lock()
+			fake.commands = append(fake.commands, command)
+			fake.mu.Unlock()
+			if fake.respond != nil {
+				for _, line := range fake.respond(command) {
+					output <- line
+				}
+			}
+			if command == "isready" {
+				output <- "readyok"
+			}
+		}
+		close(output)
+	}()
+	go func() {
+		for line := range output {
+			io.WriteString(outWriter, line+"\n")
+		}
+		outWriter.Close()
+	}()
+
+	var stdin io.WriteCloser = inWriter
+	engine := &Engine{
+		Executable: "fake",
+		Stdin:      &stdin,
+		Stdout:     bufio.NewReader(outReader),
+		Depth:      2,
+		Param:      map[string]string{},
+	}
+	return engine, fake
+}
+
+// sent returns the commands received by the fake engine so far
+func (fake *fakeEngine) sent() []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return append([]string{}, fake.commands...)
+}
+
+// fakeExecutable is a shell script speaking just enough UCI to start up
+const fakeExecutable = `#!/bin/sh
+while read line; do
+	case "$line" in
+		uci) echo "id name fake"; echo "uciok";;
+		isready) echo "readyok";;
+		quit) exit 0;;
+	esac
+done
+`
+
+// writeFakeExecutable writes a script to a temporary directory and returns its path
+func writeFakeExecutable(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "fake-engine")
+	err := ioutil.WriteFile(path, []byte(script), 0755)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return path
+}
+
+// stockfishHandshake answers 'uci' like a stripped down Stockfish 12
+func stockfishHandshake(command string) []string {
+	if command == "uci" {
+		return []string{
+			"id name Stockfish 12",
+			"id author the Stockfish developers (see AUTHORS file)",
+			"",
+			"option name Contempt type spin default 24 min -100 max 100",
+			"option name Clear Hash type button",
+			"option name UCI_AnalyseMode type check default false",
+			"uciok",
+		}
+	}
+	return nil
+}
 
 func TestParseInfo(t *testing.T) {
 	var tests = []struct {
@@ -41,10 +152,98 @@ func TestParseInfo(t *testing.T) {
 				Pv:     "h3g3 g6f7 g3c7 b5d7 d1d7 f7g6 c7g3 g6h5 e6f4",
 			},
 		},
+		{
+			"info depth 20 seldepth 27 multipv 1 score cp 32 wdl 120 850 30 nodes 912345 nps 1200000 tbhits 0 time 760 pv e2e4 e7e5",
+			&Info{
+				Depth:    20,
+				Seldepth: 27,
+				Multipv:  1,
+				Score: Score{
+					Eval:  "cp",
+					Value: 32,
+				},
+				Nodes:  912345,
+				Nps:    1200000,
+				Tbhits: 0,
+				Time:   760,
+				Pv:     "e2e4 e7e5",
+				WDL: WDL{
+					Win:  120,
+					Draw: 850,
+					Loss: 30,
+				},
+			},
+		},
+		{
+			"info depth 58 seldepth 96 multipv 1 score cp 24 nodes 48123456789 nps 6684000 tbhits 3123456789 time 7200000 pv e2e4",
+			&Info{
+				Depth:    58,
+				Seldepth: 96,
+				Multipv:  1,
+				Score: Score{
+					Eval:  "cp",
+					Value: 24,
+				},
+				Nodes:  48123456789,
+				Nps:    6684000,
+				Tbhits: 3123456789,
+				Time:   7200000,
+				Pv:     "e2e4",
+			},
+		},
+		{
+			"info depth 18 seldepth 24 multipv 1 score cp 34 lowerbound nodes 180000 nps 900000 tbhits 0 time 200 pv e2e4",
+			&Info{
+				Depth:    18,
+				Seldepth: 24,
+				Multipv:  1,
+				Score: Score{
+					Eval:  "cp",
+					Value: 34,
+					Bound: "lower",
+				},
+				Nodes:  180000,
+				Nps:    900000,
+				Tbhits: 0,
+				Time:   200,
+				Pv:     "e2e4",
+			},
+		},
+		{
+			"info depth 18 seldepth 24 multipv 1 score cp 28 upperbound nodes 190000 nps 900000 tbhits 0 time 210 pv d2d4",
+			&Info{
+				Depth:    18,
+				Seldepth: 24,
+				Multipv:  1,
+				Score: Score{
+					Eval:  "cp",
+					Value: 28,
+					Bound: "upper",
+				},
+				Nodes:  190000,
+				Nps:    900000,
+				Tbhits: 0,
+				Time:   210,
+				Pv:     "d2d4",
+			},
+		},
 		{
 			"info string NNUE evaluation using nn-82215d0fd0df.nnue enabled",
 			&Info{},
 		},
+		{
+			"info string Available processors: 0-3",
+			&Info{},
+		},
+		{
+			"info depth 0 score mate 0",
+			&Info{
+				Score: Score{
+					Eval:  "mate",
+					Value: 0,
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		actual, err := ParseInfo(tt.input)
@@ -57,6 +256,158 @@ func TestParseInfo(t *testing.T) {
 	}
 }
 
+// regexp based implementation of ParseInfo before the single pass tokenizer, kept as a
+// reference for TestParseInfoParity and BenchmarkParseInfoRegexp
+var (
+	terminalRegex = regexp.MustCompile(`^info depth 0 score (?P<eval>cp|mate) (?P<value>-?\d+)$`)
+	pvRegex       = regexp.MustCompile(PVRegex)
+	// Example values:
+	// score cp -100        <- engine is behind 100 centipawns
+	// score mate 3         <- engine has big lead or checkmated opponent
+	scoreRegex = regexp.MustCompile(`score (?P<eval>\w+) (?P<value>-?\d+)( (?P<bound>lower|upper)bound)?`)
+	wdlRegex   = regexp.MustCompile(` wdl (?P<win>\d+) (?P<draw>\d+) (?P<loss>\d+)`)
+
+	singleValueFields  = []string{"depth", "seldepth", "multipv", "nodes", "nps", "tbhits", "time"}
+	singleValueRegexes = compileSingleValueRegexes(singleValueFields)
+)
+
+// compileSingleValueRegexes compiles the regular expressions of the single value fields of an
+// info line, i.e. 'depth 20'
+func compileSingleValueRegexes(fields []string) []*regexp.Regexp {
+	var regexes []*regexp.Regexp
+	for _, field := range fields {
+		regexes = append(regexes, regexp.MustCompile(field+` (?P<value>\d+)`))
+	}
+	return regexes
+}
+
+func parseInfoRegexp(line string) (*Info, error) {
+	var err error
+	result := &Info{}
+
+	if strings.HasPrefix(line, "info string ") {
+		return result, nil
+	}
+
+	// no legal moves in the position: "info depth 0 score mate 0" (checkmate) or "info depth 0 score cp 0" (stalemate)
+	if match := terminalRegex.FindStringSubmatch(line); match != nil {
+		result.Score.Eval = match[1]
+		result.Score.Value, err = strconv.Atoi(match[2])
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	matches := pvRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, fmt.Errorf("Could not parse pv: %s", line)
+	}
+	result.Pv = matches[1]
+
+	matches = scoreRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, fmt.Errorf("Could not parse score: %s", line)
+	}
+	result.Score.Eval = matches[1]
+	result.Score.Value, err = strconv.Atoi(matches[2])
+	if err != nil {
+		return nil, err
+	}
+	result.Score.Bound = matches[4]
+
+	// optional, i.e. wdl 120 850 30
+	if match := wdlRegex.FindStringSubmatch(line); match != nil {
+		result.WDL.Win, _ = strconv.Atoi(match[1])
+		result.WDL.Draw, _ = strconv.Atoi(match[2])
+		result.WDL.Loss, _ = strconv.Atoi(match[3])
+	}
+
+	for i, field := range singleValueFields {
+		matches = singleValueRegexes[i].FindStringSubmatch(line)
+		if matches == nil {
+			return nil, fmt.Errorf("Could not parse %s: %s", field, line)
+		}
+		// node counts of long searches exceed the range of a 32 bit int
+		value, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if field == "depth" {
+			result.Depth = int(value)
+		} else if field == "seldepth" {
+			result.Seldepth = int(value)
+		} else if field == "multipv" {
+			result.Multipv = int(value)
+		} else if field == "nodes" {
+			result.Nodes = value
+		} else if field == "nps" {
+			result.Nps = value
+		} else if field == "tbhits" {
+			result.Tbhits = value
+		} else if field == "time" {
+			result.Time = int(value)
+		}
+	}
+
+	return result, nil
+}
+
+func TestParseInfoParity(t *testing.T) {
+	lines := []string{
+		"info depth 2 seldepth 3 multipv 1 score cp -656 nodes 43 nps 43000 tbhits 0 time 1 pv g7g6 h3g3 g6f7",
+		"info depth 10 seldepth 12 multipv 1 score mate 5 nodes 2378 nps 1189000 tbhits 0 time 2 pv h3g3 g6f7 g3c7 b5d7 d1d7 f7g6 c7g3 g6h5 e6f4",
+		"info depth 20 seldepth 27 multipv 1 score cp 32 wdl 120 850 30 nodes 912345 nps 1200000 tbhits 0 time 760 pv e2e4 e7e5",
+		"info depth 58 seldepth 96 multipv 1 score cp 24 nodes 48123456789 nps 6684000 tbhits 3123456789 time 7200000 pv e2e4",
+		"info depth 2 seldepth 2 multipv 1 score cp 60 upperbound nodes 50 nps 25000 tbhits 0 time 2 pv d2d4",
+		"info depth 18 seldepth 24 multipv 1 score cp 34 lowerbound nodes 180000 nps 900000 tbhits 0 time 200 pv e2e4",
+		"info depth 18 seldepth 24 multipv 1 score mate 7 upperbound nodes 180000 nps 900000 tbhits 0 time 200 pv e2e4",
+		"info depth 2 seldepth 2 multipv 2 score mate -3 nodes 50 nps 25000 hashfull 12 tbhits 0 time 2 pv e7e8q d7e8",
+		"info depth 1 seldepth 1 multipv 1 score cp 12 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4 string done",
+		"info depth 0 score mate 0",
+		"info depth 0 score cp 0",
+		"info string NNUE evaluation using nn-6877cd24400e.nnue enabled",
+		"info depth 3 seldepth 3 multipv 1 score cp 20 nodes 60 nps 60000 tbhits 0 pv e2e4",
+		"info depth 3 seldepth 3 multipv 1 nodes 60 nps 60000 tbhits 0 time 1 pv e2e4",
+		"info depth 3 seldepth 3 multipv 1 score cp 20 nodes 60 nps 60000 tbhits 0 time 1",
+		"info depth 3 seldepth 3 multipv 1 score cp 20 nodes 60 nps 60000 tbhits 0 time 1 pv",
+		"info depth 3 seldepth 3 multipv 1 score cp 20 nodes 99999999999999999999 nps 60000 tbhits 0 time 1 pv e2e4",
+	}
+	for _, line := range lines {
+		expected, expectedErr := parseInfoRegexp(line)
+		actual, err := ParseInfo(line)
+		if (err == nil) != (expectedErr == nil) {
+			t.Errorf("ParseInfo(\"%s\"): expected error %v, actual %v", line, expectedErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("ParseInfo(\"%s\"): expected %+v, actual %+v", line, expected, actual)
+		}
+	}
+}
+
+func BenchmarkParseInfo(b *testing.B) {
+	line := "info depth 24 seldepth 33 multipv 1 score cp 31 wdl 95 870 35 nodes 2841236 nps 1520000 tbhits 0 time 1869 pv e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1 f8e7"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseInfo(line)
+		if err != nil {
+			b.Fatalf(err.Error())
+		}
+	}
+}
+
+func BenchmarkParseInfoRegexp(b *testing.B) {
+	line := "info depth 24 seldepth 33 multipv 1 score cp 31 wdl 95 870 35 nodes 2841236 nps 1520000 tbhits 0 time 1869 pv e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1 f8e7"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := parseInfoRegexp(line)
+		if err != nil {
+			b.Fatalf(err.Error())
+		}
+	}
+}
+
 func TestBestMove(t *testing.T) {
 	var tests = []struct {
 		input    string
@@ -76,6 +427,27 @@ func TestBestMove(t *testing.T) {
 				Ponder: "",
 			},
 		},
+		{
+			"bestmove d2d4 ponder a7a6 extra tokens",
+			&BestMove{
+				Move:   "d2d4",
+				Ponder: "a7a6",
+			},
+		},
+		{
+			"bestmove d2d4 draw ponder a7a6",
+			&BestMove{
+				Move:   "d2d4",
+				Ponder: "a7a6",
+			},
+		},
+		{
+			"bestmove d2d4 ponder",
+			&BestMove{
+				Move:   "d2d4",
+				Ponder: "",
+			},
+		},
 	}
 	for _, tt := range tests {
 		actual, err := ParseBestMove(tt.input)
@@ -86,4 +458,2144 @@ func TestBestMove(t *testing.T) {
 			t.Errorf("ParseBestMove(\"%s\"): expected %v, actual %v", tt.input, tt.expected, actual)
 		}
 	}
+
+	for _, input := range []string{"bestmove", "info depth 1"} {
+		if _, err := ParseBestMove(input); err == nil {
+			t.Errorf("ParseBestMove(\"%s\"): expected error", input)
+		}
+	}
+}
+
+func TestSearchStats(t *testing.T) {
+	info, err := ParseInfo("info depth 20 seldepth 28 multipv 1 score cp 35 nodes 3000000 nps 1400000 tbhits 0 time 2000 pv e2e4 e7e5")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	bestMove := &BestMove{Move: "e2e4", Info: info}
+	expected := SearchStats{
+		Depth:    20,
+		Seldepth: 28,
+		Nodes:    3000000,
+		Nps:      1500000,
+		Elapsed:  2 * time.Second,
+	}
+	if actual := bestMove.Stats(); *actual != expected {
+		t.Errorf("Stats(): expected %v, actual %v", expected, actual)
+	}
+
+	if actual := (&BestMove{Move: "e2e4"}).Stats(); *actual != (SearchStats{}) {
+		t.Errorf("Stats() without info: expected %v, actual %v", SearchStats{}, actual)
+	}
+}
+
+func TestBestMoveSideToMove(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{"bestmove e2e4"}
+		}
+		return nil
+	})
+
+	var tests = []struct {
+		set      func() error
+		expected Color
+	}{
+		{func() error { return nil }, ""},
+		{func() error { return engine.SetPosition([]string{"e2e4"}) }, ColorBlack},
+		{func() error { return engine.SetFENPosition("4k3/8/8/8/8/8/8/4K3 b - - 0 1") }, ColorBlack},
+		{func() error { return engine.SetFENPositionWithMoves("4k3/8/8/8/8/8/8/4K3 b - - 0 1", []string{"e8d8"}) }, ColorWhite},
+		{engine.Flip, ColorBlack},
+	}
+	for i, tt := range tests {
+		if err := tt.set(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		bestMove, err := engine.BestMove()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if bestMove.SideToMove != tt.expected {
+			t.Errorf("BestMove (%d): expected side to move %q, actual %q", i, tt.expected, bestMove.SideToMove)
+		}
+	}
+}
+
+func TestCheckmated(t *testing.T) {
+	var tests = []struct {
+		info     string
+		bestMove string
+		expected bool
+	}{
+		{"info depth 0 score mate 0", "bestmove (none)", true},
+		{"info depth 0 score cp 0", "bestmove (none)", false},
+		{"info depth 5 seldepth 5 multipv 1 score mate 1 nodes 200 nps 20000 tbhits 0 time 10 pv d8h4", "bestmove d8h4", false},
+	}
+	for _, tt := range tests {
+		engine, _ := newFakeEngine(func(command string) []string {
+			if command == "go depth 2" {
+				return []string{tt.info, tt.bestMove}
+			}
+			return nil
+		})
+		bestMove, err := engine.BestMove()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if actual := bestMove.Checkmated(); actual != tt.expected {
+			t.Errorf("Checkmated() after \"%s\": expected %v, actual %v", tt.info, tt.expected, actual)
+		}
+		if actual := bestMove.Info.Score.Checkmated(); actual != tt.expected {
+			t.Errorf("Score.Checkmated() after \"%s\": expected %v, actual %v", tt.info, tt.expected, actual)
+		}
+	}
+}
+
+func TestGetScore(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		switch command {
+		case "go depth 8":
+			return []string{
+				"info depth 7 seldepth 9 multipv 1 score cp 41 nodes 4000 nps 400000 tbhits 0 time 10 pv e2e4",
+				"info depth 8 seldepth 10 multipv 1 score cp 37 nodes 8000 nps 400000 tbhits 0 time 20 pv d2d4",
+				"bestmove d2d4",
+			}
+		case "go depth 3":
+			return []string{"bestmove e2e4"}
+		}
+		return nil
+	})
+
+	score, err := engine.GetScore(8)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if expected := (Score{Eval: "cp", Value: 37}); score != expected {
+		t.Errorf("GetScore(8): expected %v, actual %v", expected, score)
+	}
+
+	if _, err := engine.GetScore(3); err == nil {
+		t.Errorf("GetScore(3): expected error without info line")
+	}
+	if _, err := engine.GetScore(0); err == nil {
+		t.Errorf("GetScore(0): expected error")
+	}
+}
+
+func TestScoreBothSides(t *testing.T) {
+	flipped := false
+	engine, fake := newFakeEngine(func(command string) []string {
+		switch command {
+		case "flip":
+			flipped = !flipped
+		case "go depth 10":
+			if flipped {
+				return []string{"info depth 10 seldepth 12 multipv 1 score cp 18 nodes 9000 nps 450000 tbhits 0 time 20 pv e7e5", "bestmove e7e5"}
+			}
+			return []string{"info depth 10 seldepth 12 multipv 1 score cp 30 nodes 9000 nps 450000 tbhits 0 time 20 pv e2e4", "bestmove e2e4"}
+		}
+		return nil
+	})
+
+	score, flippedScore, err := engine.ScoreBothSides(10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if score.Value != 30 || flippedScore.Value != 18 {
+		t.Errorf("ScoreBothSides(10): expected cp 30 and cp 18, actual %v and %v", score, flippedScore)
+	}
+	if actual := ScoreAsymmetry(score, flippedScore); actual != -12 {
+		t.Errorf("ScoreAsymmetry(%v, %v): expected -12, actual %d", score, flippedScore, actual)
+	}
+	if flipped {
+		t.Errorf("ScoreBothSides(10): expected the position to be flipped back, actual %v", fake.sent())
+	}
+}
+
+func TestAnalyzeAfter(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go depth 6" {
+			return []string{"info depth 6 seldepth 8 multipv 1 score cp -40 nodes 900 nps 90000 tbhits 0 time 10 pv g1f3", "bestmove g1f3"}
+		}
+		return nil
+	})
+	if _, err := engine.AnalyzeAfter([]string{"e7e5"}, 6); err == nil {
+		t.Errorf("AnalyzeAfter without position: expected error")
+	}
+	engine.SetPosition([]string{"e2e4"})
+
+	bestMove, err := engine.AnalyzeAfter([]string{"e7e5"}, 6)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "g1f3" || bestMove.SideToMove != ColorWhite {
+		t.Errorf("AnalyzeAfter([e7e5], 6): expected g1f3 for white, actual %s for %s", bestMove.Move, bestMove.SideToMove)
+	}
+	if _, err := engine.AnalyzeAfter([]string{"e7e5", "e1e3"}, 6); err == nil {
+		t.Errorf("AnalyzeAfter([e7e5 e1e3], 6): expected error for illegal move")
+	}
+
+	expected := []string{
+		"position startpos moves e2e4",
+		"isready",
+		"position fen " + StartFEN + " moves e2e4 e7e5",
+		"isready",
+		"go depth 6",
+		"position startpos moves e2e4",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("AnalyzeAfter: expected %v, actual %v", expected, actual)
+	}
+	if engine.sideToMove != ColorBlack {
+		t.Errorf("AnalyzeAfter: expected black to move after restore, actual %s", engine.sideToMove)
+	}
+}
+
+func TestNormalizedPawns(t *testing.T) {
+	var tests = []struct {
+		name     string
+		score    Score
+		expected float64
+	}{
+		{"Stockfish 16.1", Score{Eval: "cp", Value: 150}, 1.5},
+		{"Stockfish 15.1", Score{Eval: "cp", Value: -80}, -0.8},
+		{"Stockfish dev-20240101-4b4bd5e2", Score{Eval: "cp", Value: 100}, 1},
+		{"Stockfish 15", Score{Eval: "cp", Value: 361}, 2.08},
+		{"Stockfish 12", Score{Eval: "cp", Value: -361}, -2.08},
+		{"Stockfish 14.1", Score{Eval: "cp", Value: 0}, 0},
+		{"Komodo 14", Score{Eval: "cp", Value: 50}, 0.5},
+		{"", Score{Eval: "cp", Value: 50}, 0.5},
+	}
+	for _, tt := range tests {
+		engine := &Engine{name: tt.name}
+		if actual := engine.NormalizedPawns(tt.score); math.Abs(actual-tt.expected) > 1e-9 {
+			t.Errorf("NormalizedPawns(%v) of %s: expected %f, actual %f", tt.score, tt.name, tt.expected, actual)
+		}
+	}
+	if actual := (Score{Eval: "mate", Value: -3}).Pawns(); !math.IsInf(actual, -1) {
+		t.Errorf("Pawns() of mate -3: expected -Inf, actual %f", actual)
+	}
+}
+
+func TestSelectiveDepthGap(t *testing.T) {
+	infos := []*Info{
+		{Depth: 1, Seldepth: 1},
+		{Depth: 2, Seldepth: 4},
+		{Depth: 3, Seldepth: 8},
+	}
+	if actual := infos[2].SelectiveDepthGap(); actual != 5 {
+		t.Errorf("SelectiveDepthGap(): expected 5, actual %d", actual)
+	}
+	if actual := AverageSelectiveDepthGap(infos); actual != 7.0/3 {
+		t.Errorf("AverageSelectiveDepthGap(): expected %f, actual %f", 7.0/3, actual)
+	}
+	if actual := AverageSelectiveDepthGap(nil); actual != 0 {
+		t.Errorf("AverageSelectiveDepthGap(nil): expected 0, actual %f", actual)
+	}
+}
+
+func TestTablebaseResult(t *testing.T) {
+	var tests = []struct {
+		line     string
+		expected TablebaseResult
+	}{
+		{"info depth 30 seldepth 2 multipv 1 score cp 19998 nodes 120 nps 1000 tbhits 40 time 1 pv e1e2", TablebaseWin},
+		{"info depth 30 seldepth 2 multipv 1 score cp -19996 nodes 120 nps 1000 tbhits 40 time 1 pv e1e2", TablebaseLoss},
+		{"info depth 30 seldepth 2 multipv 1 score mate 12 nodes 120 nps 1000 tbhits 40 time 1 pv e1e2", TablebaseWin},
+		{"info depth 30 seldepth 2 multipv 1 score cp 0 nodes 120 nps 1000 tbhits 40 time 1 pv e1e2", TablebaseDraw},
+		{"info depth 30 seldepth 2 multipv 1 score cp 1 nodes 120 nps 1000 tbhits 40 time 1 pv e1e2", TablebaseCursedWin},
+		{"info depth 30 seldepth 2 multipv 1 score cp -2 nodes 120 nps 1000 tbhits 40 time 1 pv e1e2", TablebaseBlessedLoss},
+		{"info depth 30 seldepth 2 multipv 1 score cp 150 nodes 120 nps 1000 tbhits 40 time 1 pv e1e2", TablebaseUnknown},
+		{"info depth 30 seldepth 2 multipv 1 score cp 19998 nodes 120 nps 1000 tbhits 0 time 1 pv e1e2", TablebaseUnknown},
+	}
+	for _, tt := range tests {
+		info, err := ParseInfo(tt.line)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if info.UsedTablebase() != (info.Tbhits > 0) {
+			t.Errorf("UsedTablebase(\"%s\"): expected %v, actual %v", tt.line, info.Tbhits > 0, info.UsedTablebase())
+		}
+		if actual := info.TablebaseResult(); actual != tt.expected {
+			t.Errorf("TablebaseResult(\"%s\"): expected %q, actual %q", tt.line, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseOption(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected *Option
+	}{
+		{
+			"option name Skill Level type spin default 20 min 0 max 20",
+			&Option{
+				Name:    "Skill Level",
+				Type:    "spin",
+				Default: "20",
+				Min:     0,
+				Max:     20,
+			},
+		},
+		{
+			"option name Contempt type spin default 24 min -100 max 100",
+			&Option{
+				Name:    "Contempt",
+				Type:    "spin",
+				Default: "24",
+				Min:     -100,
+				Max:     100,
+			},
+		},
+		{
+			"option name Analysis Contempt type combo default Both var Off var White var Black var Both",
+			&Option{
+				Name:    "Analysis Contempt",
+				Type:    "combo",
+				Default: "Both",
+				Var:     []string{"Off", "White", "Black", "Both"},
+			},
+		},
+		{
+			"option name Clear Hash type button",
+			&Option{
+				Name: "Clear Hash",
+				Type: "button",
+			},
+		},
+		{
+			"option name Debug Log File type string default",
+			&Option{
+				Name: "Debug Log File",
+				Type: "string",
+			},
+		},
+	}
+	for _, tt := range tests {
+		actual, err := ParseOption(tt.input)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("ParseOption(\"%s\"): expected %v, actual %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestSetAnalyseMode(t *testing.T) {
+	engine, fake := newFakeEngine(stockfishHandshake)
+	engine.Param["Contempt"] = "10"
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	err = engine.SetAnalyseMode(true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = engine.SetAnalyseMode(false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []string{
+		"uci",
+		"setoption name UCI_AnalyseMode value true",
+		"isready",
+		"setoption name Contempt value 0",
+		"isready",
+		"setoption name UCI_AnalyseMode value false",
+		"isready",
+		"setoption name Contempt value 10",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SetAnalyseMode: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestSetPositionLong(t *testing.T) {
+	engine, fake := newFakeEngine(nil)
+	defer func(max int) { MaxPositionMoves = max }(MaxPositionMoves)
+	MaxPositionMoves = 2
+
+	err := engine.SetPosition([]string{"e2e4", "e7e5"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = engine.SetPosition([]string{"e2e4", "e7e5", "g1f3"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{
+		"position startpos moves e2e4 e7e5",
+		"isready",
+		"position fen rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1 moves e7e5 g1f3",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SetPosition: expected %v, actual %v", expected, actual)
+	}
+
+	err = engine.SetPosition([]string{"e2e4", "e2e4", "g1f3", "b8c6"})
+	if err == nil {
+		t.Errorf("SetPosition: expected error for illegal move")
+	}
+}
+
+func TestSetMode(t *testing.T) {
+	engine, fake := newFakeEngine(stockfishHandshake)
+	engine.Param["Contempt"] = "10"
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	err = engine.SetMode(ModeAnalysis)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = engine.SetMode(ModePlay)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{
+		"uci",
+		"setoption name UCI_AnalyseMode value true",
+		"isready",
+		"setoption name Contempt value 0",
+		"isready",
+		"setoption name UCI_AnalyseMode value false",
+		"isready",
+		"setoption name Contempt value 10",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SetMode: expected %v, actual %v", expected, actual)
+	}
+	if engine.Param["Contempt"] != "10" || engine.Param["UCI_AnalyseMode"] != "false" {
+		t.Errorf("SetMode: unexpected Param %v", engine.Param)
+	}
+
+	if err := engine.SetMode("blitz"); err == nil {
+		t.Errorf("SetMode(\"blitz\"): expected error")
+	}
+}
+
+func TestResetToDefaults(t *testing.T) {
+	engine, fake := newFakeEngine(stockfishHandshake)
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	engine.Param["Contempt"] = "10"
+	engine.Param["UCI_AnalyseMode"] = "true"
+
+	err = engine.ResetToDefaults()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{
+		"uci",
+		"setoption name UCI_AnalyseMode value false",
+		"isready",
+		"setoption name Ponder value false",
+		"isready",
+		"setoption name Contempt value 0",
+		"isready",
+		"ucinewgame",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ResetToDefaults: expected %v, actual %v", expected, actual)
+	}
+	if !reflect.DeepEqual(engine.Param, DefaultParam) {
+		t.Errorf("ResetToDefaults: expected Param %v, actual %v", DefaultParam, engine.Param)
+	}
+	if _, ok := engine.FailedOptions()["Hash"]; !ok {
+		t.Errorf("ResetToDefaults: expected unadvertised Hash to fail, actual %v", engine.FailedOptions())
+	}
+
+	engine.raw = true
+	engine.Param = map[string]string{"Contempt": "10"}
+	err = engine.ResetToDefaults()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected = append(expected, "setoption name Contempt value 24", "isready", "ucinewgame", "isready")
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ResetToDefaults raw: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestSetOptionButton(t *testing.T) {
+	engine, fake := newFakeEngine(stockfishHandshake)
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	err = engine.PressButton("Clear Hash")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = engine.SetOption("Clear Hash", "")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	engine.SetOptionNoWait("Contempt", "5")
+	err = engine.IsReady()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{
+		"uci",
+		"setoption name Clear Hash",
+		"isready",
+		"setoption name Clear Hash",
+		"isready",
+		"setoption name Contempt value 5",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SetOption: expected %v, actual %v", expected, actual)
+	}
+
+	if err := engine.PressButton("Contempt"); err == nil {
+		t.Errorf("PressButton(\"Contempt\"): expected error")
+	}
+}
+
+func TestSetOptionVerified(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "setoption name UCI_AnalyseMode value true" {
+			return []string{"No such option: UCI_AnalyseMode"}
+		}
+		return stockfishHandshake(command)
+	})
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	err = engine.SetOptionVerified("Contempt", "5")
+	if err != nil {
+		t.Errorf("SetOptionVerified(\"Contempt\"): %s", err)
+	}
+	for _, name := range []string{"Contemp", "UCI_AnalyseMode"} {
+		if err := engine.SetOptionVerified(name, "true"); err == nil {
+			t.Errorf("SetOptionVerified(\"%s\"): expected error", name)
+		}
+	}
+	expected := []string{
+		"uci",
+		"setoption name Contempt value 5",
+		"isready",
+		"setoption name UCI_AnalyseMode value true",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SetOptionVerified: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	engine, fake := newFakeEngine(stockfishHandshake)
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	err = engine.ApplyConfig(map[string]string{
+		"Contempt":        "12",
+		"UCI_AnalyseMode": "yes",
+		"Threads":         "4",
+	})
+	configErr, ok := err.(*ConfigError)
+	if !ok {
+		t.Fatalf("ApplyConfig: expected *ConfigError, actual %v", err)
+	}
+	if len(configErr.Errors) != 2 || configErr.Errors["UCI_AnalyseMode"] == nil || configErr.Errors["Threads"] == nil {
+		t.Errorf("ApplyConfig: expected errors for UCI_AnalyseMode and Threads, actual %s", configErr)
+	}
+	expected := []string{
+		"uci",
+		"setoption name Contempt value 12",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ApplyConfig: expected %v, actual %v", expected, actual)
+	}
+	if engine.Param["Contempt"] != "12" || engine.Param["Threads"] != "" {
+		t.Errorf("ApplyConfig: unexpected Param %v", engine.Param)
+	}
+
+	err = engine.ApplyConfig(map[string]string{"UCI_AnalyseMode": "true"})
+	if err != nil {
+		t.Errorf("ApplyConfig: %s", err)
+	}
+}
+
+func TestHandshakeInfoLines(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "uci" {
+			return []string{
+				"info string Loading network nn-82215d0fd0df.nnue",
+				"id name Stockfish 12",
+				"info string NNUE evaluation using nn-82215d0fd0df.nnue enabled",
+				"id author the Stockfish developers (see AUTHORS file)",
+				"option name Hash type spin default 16 min 1 max 33554432",
+				"info string Available processors: 0-3",
+				"option name Ponder type check default false",
+				"uciok",
+			}
+		}
+		return nil
+	})
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if engine.Name() != "Stockfish 12" || engine.Author() != "the Stockfish developers (see AUTHORS file)" {
+		t.Errorf("waitForUCIOK: unexpected id %s by %s", engine.Name(), engine.Author())
+	}
+	if len(engine.options) != 2 || !engine.HasOption("Hash") || !engine.HasOption("Ponder") {
+		t.Errorf("waitForUCIOK: expected options Hash and Ponder, actual %v", engine.options)
+	}
+	if err := engine.IsReady(); err != nil {
+		t.Errorf("IsReady() after handshake: %s", err)
+	}
+}
+
+func TestSetDebug(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{
+				"info string Hash table allocation: Windows large pages not used.",
+				"Total 120 Hits 75 hit rate (%) 62",
+				"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"bestmove e2e4",
+			}
+		}
+		return nil
+	})
+	err := engine.SetDebug(true)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	bestMove, err := engine.BestMove()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "e2e4" || bestMove.Info == nil || bestMove.Info.Depth != 1 {
+		t.Errorf("BestMove() in debug mode: unexpected %+v", bestMove)
+	}
+	expected := []string{
+		"info string Hash table allocation: Windows large pages not used.",
+		"Total 120 Hits 75 hit rate (%) 62",
+	}
+	if actual := engine.DebugLines(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("DebugLines(): expected %v, actual %v", expected, actual)
+	}
+
+	err = engine.SetDebug(false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	engine.BestMove()
+	if actual := engine.DebugLines(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("DebugLines() after debug off: expected %v, actual %v", expected, actual)
+	}
+	if sent := fake.sent(); sent[0] != "debug on" || sent[3] != "debug off" {
+		t.Errorf("SetDebug: unexpected commands %v", sent)
+	}
+}
+
+func TestHasOption(t *testing.T) {
+	engine, _ := newFakeEngine(stockfishHandshake)
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var tests = []struct {
+		name     string
+		expected bool
+	}{
+		{"Contempt", true},
+		{"contempt", true},
+		{"CLEAR HASH", true},
+		{"UCI_ShowWDL", false},
+		{"Clear", false},
+	}
+	for _, tt := range tests {
+		if actual := engine.HasOption(tt.name); actual != tt.expected {
+			t.Errorf("HasOption(\"%s\"): expected %v, actual %v", tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestSetAnalyseModeUnsupported(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "uci" {
+			return []string{"id name Minimal", "uciok"}
+		}
+		return nil
+	})
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if engine.SetAnalyseMode(true) == nil {
+		t.Errorf("SetAnalyseMode(true): expected error for engine without UCI_AnalyseMode")
+	}
+}
+
+func TestNewEngineRaw(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "commands")
+	script := strings.Replace(fakeExecutable, "while read line; do\n", "while read line; do\n\techo \"$line\" >> "+log+"\n", 1)
+	engine, err := NewEngineRaw(writeFakeExecutable(t, script), 4, map[string]string{"Hash": "32"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	engine.Put("quit")
+	<-engine.exited
+
+	content, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{"uci", "isready", "setoption name Hash value 32", "isready", "quit"}
+	if actual := strings.Split(strings.TrimSpace(string(content)), "\n"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("NewEngineRaw: expected commands %v, actual %v", expected, actual)
+	}
+}
+
+func TestCopyProtection(t *testing.T) {
+	var tests = []struct {
+		name     string
+		lines    []string
+		expected error
+	}{
+		{"none", nil, nil},
+		{"ok", []string{"copyprotection checking", "copyprotection ok"}, nil},
+		{"error", []string{"copyprotection checking", "copyprotection error"}, ErrCopyProtection},
+	}
+	for _, tt := range tests {
+		engine, _ := newFakeEngine(func(command string) []string {
+			if command == "uci" {
+				return append([]string{"id name Protected", "uciok"}, tt.lines...)
+			}
+			return nil
+		})
+		engine.Ponder = true
+		if err := engine.initialize(); err != tt.expected {
+			t.Errorf("initialize() with copy protection %s: expected %v, actual %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestFailedOptions(t *testing.T) {
+	script := strings.Replace(fakeExecutable, `uci) echo "id name fake";`, `uci) echo "id name fake"; echo "option name Hash type spin default 16 min 1 max 1024"; echo "option name Threads type spin default 1 min 1 max 512";`, 1)
+	script = strings.Replace(script, "\t\tquit) exit 0;;\n", "\t\tsetoption\\ name\\ Threads*) echo \"No such option: Threads\";;\n\t\tquit) exit 0;;\n", 1)
+	executable := writeFakeExecutable(t, script)
+
+	engine, err := NewEngineRaw(executable, 4, map[string]string{"Hash": "32", "Threads": "2", "Contempt": "10"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer engine.Close()
+	if applied := engine.AppliedOptions(); !reflect.DeepEqual(applied, map[string]string{"Hash": "32"}) {
+		t.Errorf("AppliedOptions(): expected Hash, actual %v", applied)
+	}
+	failed := engine.FailedOptions()
+	if len(failed) != 2 || failed["Threads"] == nil || failed["Contempt"] == nil {
+		t.Errorf("FailedOptions(): expected Threads and Contempt, actual %v", failed)
+	}
+	if err := engine.IsReady(); err != nil {
+		t.Errorf("IsReady() after rejected option: %s", err)
+	}
+
+	_, err = NewEngineStrict(executable, 4, map[string]string{"Hash": "32"})
+	if err == nil {
+		t.Errorf("NewEngineStrict: expected error for the unsupported default options")
+	}
+}
+
+func TestRestart(t *testing.T) {
+	engine, err := NewEngineWithAllOptions(writeFakeExecutable(t, fakeExecutable), 4, false, map[string]string{"Hash": "32"}, false, -10, 10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !engine.IsAlive() {
+		t.Fatalf("IsAlive(): expected true after start")
+	}
+
+	engine.cmd.Process.Kill()
+	select {
+	case <-engine.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("engine process did not exit after kill")
+	}
+	if engine.IsAlive() {
+		t.Fatalf("IsAlive(): expected false after kill")
+	}
+
+	err = engine.Restart()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !engine.IsAlive() {
+		t.Errorf("IsAlive(): expected true after Restart")
+	}
+	if engine.Depth != 4 || engine.Param["Hash"] != "32" {
+		t.Errorf("Restart(): expected configuration to be kept, got depth %d and param %v", engine.Depth, engine.Param)
+	}
+	err = engine.IsReady()
+	if err != nil {
+		t.Errorf("IsReady() after Restart: %s", err)
+	}
+}
+
+func TestParseEvaluation(t *testing.T) {
+	output := []string{
+		"     Term    |    White    |    Black    |    Total",
+		"             |   MG    EG  |   MG    EG  |   MG    EG",
+		" ------------+-------------+-------------+------------",
+		"    Material |   ----  ----|   ----  ----|  0.00  0.00",
+		"   Imbalance |   ----  ----|   ----  ----|  0.00  0.00",
+		"    Mobility | -0.88 -1.24 | -0.88 -1.24 |  0.00  0.00",
+		" King safety |  0.91 -0.10 |  0.91 -0.10 |  0.00  0.00",
+		" ------------+-------------+-------------+------------",
+		"       Total |   ----  ----|   ----  ----|  0.18  0.22",
+		"",
+		"Classical evaluation: 0.18 (white side)",
+		"NNUE evaluation:      0.08 (white side)",
+		"Final evaluation: +0.08 (white side)",
+	}
+	expected := &Evaluation{
+		Final: 0.08,
+		Terms: map[string]EvalTerm{
+			"Material":    {MG: 0, EG: 0},
+			"Imbalance":   {MG: 0, EG: 0},
+			"Mobility":    {MG: 0, EG: 0},
+			"King safety": {MG: 0, EG: 0},
+			"Total":       {MG: 0.18, EG: 0.22},
+		},
+	}
+
+	actual, err := ParseEvaluation(output)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ParseEvaluation: expected %v, actual %v", expected, actual)
+	}
+
+	_, err = ParseEvaluation([]string{"Final evaluation: none (in check)"})
+	if err == nil {
+		t.Errorf("ParseEvaluation: expected error for position in check")
+	}
+}
+
+func TestBestMoveUntil(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "stop" {
+			return []string{"bestmove d2d4 ponder d7d5"}
+		}
+		if command == "go depth 2" {
+			return []string{
+				"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"info depth 2 seldepth 2 multipv 1 score cp 30 nodes 400 nps 40000 tbhits 0 time 10 pv d2d4 d7d5",
+			}
+		}
+		return nil
+	})
+
+	bestMove, err := engine.BestMoveUntil(nil, 100)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "d2d4" || bestMove.Info.Nodes != 400 {
+		t.Errorf("BestMoveUntil(nil, 100): expected d2d4 after 400 nodes, actual %s after %d nodes", bestMove.Move, bestMove.Info.Nodes)
+	}
+
+	bestMove, err = engine.BestMoveUntil(func(info *Info) bool { return info.Depth >= 1 }, 0)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "d2d4" {
+		t.Errorf("BestMoveUntil(depth >= 1, 0): expected d2d4, actual %s", bestMove.Move)
+	}
+
+	stops := 0
+	for _, command := range fake.sent() {
+		if command == "stop" {
+			stops++
+		}
+	}
+	if stops != 2 {
+		t.Errorf("BestMoveUntil: expected one stop per search, sent %v", fake.sent())
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "setoption name Threads value 8" {
+			return []string{
+				"info string Available processors: 0-3",
+				"info string Using 8 threads",
+				"info string NNUE evaluation using nn-82215d0fd0df.nnue enabled",
+			}
+		}
+		return nil
+	})
+
+	err := engine.SetOption("Threads", "8")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []string{"Available processors: 0-3", "Using 8 threads"}
+	if actual := engine.Warnings(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Warnings(): expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestReadLongLine(t *testing.T) {
+	line := "info depth 30 seldepth 40 multipv 1 score cp 20 nodes 123456 nps 1000000 tbhits 0 time 123 pv e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6 e1g1 f8e7"
+	engine := &Engine{
+		Stdout: bufio.NewReaderSize(strings.NewReader(line+"\n\n  \nreadyok\n"), 16),
+	}
+
+	actual, err := engine.readLine(context.Background())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if actual != line {
+		t.Errorf("readLine(): expected %s, actual %s", line, actual)
+	}
+	actual, err = engine.readLine(context.Background())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if actual != "readyok" {
+		t.Errorf("readLine(): expected readyok, actual %s", actual)
+	}
+}
+
+func TestIsLegalMove(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go perft 1" {
+			return []string{
+				"a2a3: 1", "b2b3: 1", "c2c3: 1", "d2d3: 1", "e2e3: 1", "f2f3: 1", "g2g3: 1", "h2h3: 1",
+				"a2a4: 1", "b2b4: 1", "c2c4: 1", "d2d4: 1", "e2e4: 1", "f2f4: 1", "g2g4: 1", "h2h4: 1",
+				"b1a3: 1", "b1c3: 1", "g1f3: 1", "g1h3: 1",
+				"",
+				"Nodes searched: 20",
+				"",
+			}
+		}
+		return nil
+	})
+	engine.SetPosition(nil)
+
+	var tests = []struct {
+		move     string
+		expected bool
+	}{
+		{"e2e4", true},
+		{"g1f3", true},
+		{"e2e5", false},
+		{"e1g1", false},
+	}
+	for _, tt := range tests {
+		actual, err := engine.IsLegalMove(tt.move)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if actual != tt.expected {
+			t.Errorf("IsLegalMove(\"%s\"): expected %v, actual %v", tt.move, tt.expected, actual)
+		}
+	}
+	if sent := fake.sent(); sent[2] != "go perft 1" || sent[3] != "isready" {
+		t.Errorf("IsLegalMove: unexpected commands %v", sent)
+	}
+}
+
+func TestMoveCount(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "go perft 1" {
+			return []string{
+				"a2a3: 1", "b2b3: 1", "c2c3: 1", "d2d3: 1", "e2e3: 1", "f2f3: 1", "g2g3: 1", "h2h3: 1",
+				"a2a4: 1", "b2b4: 1", "c2c4: 1", "d2d4: 1", "e2e4: 1", "f2f4: 1", "g2g4: 1", "h2h4: 1",
+				"b1a3: 1", "b1c3: 1", "g1f3: 1", "g1h3: 1",
+				"",
+				"Nodes searched: 20",
+			}
+		}
+		return nil
+	})
+	count, err := engine.MoveCount()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 20 {
+		t.Errorf("MoveCount() from the start position: expected 20, actual %d", count)
+	}
+
+	// fool's mate
+	engine, _ = newFakeEngine(func(command string) []string {
+		if command == "go perft 1" {
+			return []string{"", "Nodes searched: 0"}
+		}
+		return nil
+	})
+	engine.SetPosition([]string{"f2f3", "e7e5", "g2g4", "d8h4"})
+	count, err = engine.MoveCount()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if count != 0 {
+		t.Errorf("MoveCount() when checkmated: expected 0, actual %d", count)
+	}
+}
+
+func TestResendPosition(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if strings.HasPrefix(command, "go ") {
+			return []string{"bestmove e7e5"}
+		}
+		return nil
+	})
+	engine.ResendPosition = true
+	engine.BestMove()
+	engine.SetPosition([]string{"e2e4"})
+	engine.BestMove()
+	engine.Flip()
+	engine.BestMove()
+
+	expected := []string{
+		"go depth 2",
+		"position startpos moves e2e4",
+		"isready",
+		"position startpos moves e2e4",
+		"isready",
+		"go depth 2",
+		"flip",
+		"isready",
+		"position startpos moves e2e4",
+		"flip",
+		"isready",
+		"go depth 2",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ResendPosition: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestProtocolDesync(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if strings.HasPrefix(command, "position ") {
+			// left over from a search which was not read to its end
+			return []string{
+				"info depth 12 seldepth 14 multipv 1 score cp 20 nodes 9000 nps 450000 tbhits 0 time 20 pv e2e4",
+				"bestmove e2e4",
+			}
+		}
+		if command == "go depth 2" {
+			return []string{"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4"}
+		}
+		return nil
+	})
+
+	err := engine.SetPosition([]string{"e2e4"})
+	if !errors.Is(err, ErrProtocolDesync) {
+		t.Fatalf("SetPosition with stray search output: expected ErrProtocolDesync, actual %v", err)
+	}
+	expected := "Engine output out of sync: expected readyok, received info depth 12 seldepth 14 multipv 1 score cp 20 nodes 9000 nps 450000 tbhits 0 time 20 pv e2e4"
+	if err.Error() != expected {
+		t.Errorf("SetPosition with stray search output: expected error %s, actual %s", expected, err)
+	}
+
+	// the engine is in sync again after the fence
+	if err := engine.NewGame(); err != nil {
+		t.Errorf("NewGame() after desync: %s", err)
+	}
+	// search output is expected while a search started by Go is running
+	if err := engine.Go(); err != nil {
+		t.Errorf("Go(): %s", err)
+	}
+}
+
+func TestBlankAndTruncatedLines(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{
+				"",
+				"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"   ",
+				"info",
+				"bestmove e2e4",
+			}
+		}
+		return nil
+	})
+	bestMove, err := engine.BestMove()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "e2e4" || bestMove.Info == nil || bestMove.Info.Depth != 1 || bestMove.Info.Pv != "e2e4" {
+		t.Errorf("BestMove() with blank and truncated lines: unexpected %+v (info %+v)", bestMove, bestMove.Info)
+	}
+
+	info, err := ParseInfo("info")
+	if err != nil {
+		t.Fatalf("ParseInfo(\"info\"): %s", err)
+	}
+	if !reflect.DeepEqual(info, &Info{}) {
+		t.Errorf("ParseInfo(\"info\"): expected an empty info, actual %+v", info)
+	}
+}
+
+func TestBestMoveVerbose(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{
+				"info string NNUE evaluation using nn-82215d0fd0df.nnue enabled",
+				"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"info depth 2 seldepth 2 multipv 1 score cp 60 upperbound nodes 50 nps 25000 tbhits 0 time 2 pv d2d4",
+				"info depth 2 seldepth 3 multipv 1 score cp 35 nodes 80 nps 40000 tbhits 0 time 2 pv e2e4 e7e5",
+				"bestmove e2e4 ponder e7e5",
+			}
+		}
+		return nil
+	})
+	bestMove, infos, err := engine.BestMoveVerbose()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "e2e4" || bestMove.Ponder != "e7e5" {
+		t.Errorf("BestMoveVerbose(): expected e2e4 ponder e7e5, actual %s ponder %s", bestMove.Move, bestMove.Ponder)
+	}
+	var pvs []string
+	for _, info := range infos {
+		pvs = append(pvs, info.Pv)
+	}
+	if expected := []string{"e2e4", "d2d4", "e2e4 e7e5"}; !reflect.DeepEqual(pvs, expected) {
+		t.Errorf("BestMoveVerbose(): expected infos %v, actual %v", expected, pvs)
+	}
+}
+
+func TestBestMoveWithProgress(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{
+				"info string NNUE evaluation using nn-82215d0fd0df.nnue enabled",
+				"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"info depth 2 seldepth 2 multipv 1 score cp 30 nodes 400 nps 40000 tbhits 0 time 10 pv d2d4 d7d5",
+				"bestmove d2d4 ponder d7d5",
+			}
+		}
+		return nil
+	})
+
+	var depths []int
+	bestMove, err := engine.BestMoveWithProgress(func(info *Info) {
+		depths = append(depths, info.Depth)
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !reflect.DeepEqual(depths, []int{1, 2}) {
+		t.Errorf("BestMoveWithProgress: expected depths [1 2], actual %v", depths)
+	}
+	if bestMove.Move != "d2d4" || bestMove.Info.Depth != 2 {
+		t.Errorf("BestMoveWithProgress: expected d2d4 at depth 2, actual %s at depth %d", bestMove.Move, bestMove.Info.Depth)
+	}
+}
+
+// stockfishDisplay answers 'd' like Stockfish 12 for the position after 1. e4 e5 2. Qh5 Nc6 3. Bc4 Nf6 4. Qxf7+
+func stockfishDisplay(command string) []string {
+	if command == "d" {
+		return []string{
+			"",
+			" +---+---+---+---+---+---+---+---+",
+			" | r |   | b | q | k | b |   | r | 8",
+			" +---+---+---+---+---+---+---+---+",
+			"   a   b   c   d   e   f   g   h",
+			"",
+			"Fen: r1bqkb1r/pppp1Qpp/2n2n2/4p3/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 0 4",
+			"Key: 5A7D2C4B6E1F3A90",
+			"Checkers: f7",
+		}
+	}
+	return nil
+}
+
+func TestBestMoveWithDepths(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{
+				"info string NNUE evaluation enabled",
+				"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"info depth 1 seldepth 1 multipv 2 score cp 15 nodes 20 nps 20000 tbhits 0 time 1 pv d2d4",
+				"info depth 2 seldepth 2 multipv 1 score cp 60 upperbound nodes 50 nps 25000 tbhits 0 time 2 pv d2d4",
+				"info depth 2 seldepth 3 multipv 1 score cp 35 nodes 80 nps 40000 tbhits 0 time 2 pv e2e4 e7e5",
+				"bestmove e2e4 ponder e7e5",
+			}
+		}
+		return nil
+	})
+
+	bestMove, depths, err := engine.BestMoveWithDepths()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "e2e4" {
+		t.Errorf("BestMoveWithDepths: expected e2e4, actual %s", bestMove.Move)
+	}
+	var actual []Score
+	for i, info := range depths {
+		if info.Depth != i+1 {
+			t.Errorf("BestMoveWithDepths: expected depth %d, actual %d", i+1, info.Depth)
+		}
+		actual = append(actual, info.Score)
+	}
+	expected := []Score{{Eval: "cp", Value: 20}, {Eval: "cp", Value: 35}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("BestMoveWithDepths: expected scores %v, actual %v", expected, actual)
+	}
+}
+
+func TestMaxSearchTime(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		switch command {
+		case "go depth 2":
+			return []string{"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4"}
+		case "stop":
+			return []string{"bestmove e2e4"}
+		}
+		return nil
+	})
+	engine.MaxSearchTime = 20 * time.Millisecond
+
+	bestMove, err := engine.BestMove()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "e2e4" || bestMove.Info == nil || bestMove.Info.Depth != 1 {
+		t.Errorf("BestMove with MaxSearchTime: expected e2e4 at depth 1, actual %+v", bestMove)
+	}
+	expected := []string{"go depth 2", "stop"}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("BestMove with MaxSearchTime: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestInCheck(t *testing.T) {
+	engine, _ := newFakeEngine(stockfishDisplay)
+
+	inCheck, err := engine.InCheck()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !inCheck {
+		t.Errorf("InCheck(): expected true for \"Checkers: f7\"")
+	}
+	err = engine.IsReady()
+	if err != nil {
+		t.Errorf("IsReady() after InCheck: %s", err)
+	}
+}
+
+func TestNewEngineContextTimeout(t *testing.T) {
+	// an engine that never answers the uci handshake
+	path := writeFakeExecutable(t, "#!/bin/sh\nexec sleep 60\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := NewEngineContext(ctx, path, 2, false, map[string]string{}, false, -10, 10)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("NewEngineContext: expected %v, actual %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("NewEngineContext: expected to give up after the timeout, took %v", elapsed)
+	}
+}
+
+func TestReadLineTimeout(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	engine := &Engine{Stdout: bufio.NewReader(reader), ReadTimeout: 50 * time.Millisecond}
+
+	_, err := engine.readLine(context.Background())
+	if err != ErrReadTimeout {
+		t.Errorf("readLine() from a silent engine: expected %v, actual %v", ErrReadTimeout, err)
+	}
+
+	// the line arriving after the timeout is not lost
+	go io.WriteString(writer, "readyok\n")
+	line, err := engine.readLine(context.Background())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if line != "readyok" {
+		t.Errorf("readLine() after a timeout: expected %s, actual %s", "readyok", line)
+	}
+
+	engine.ReadTimeout = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = engine.readLine(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("readLine(ctx) from a silent engine: expected %v, actual %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestReadTimeoutSlowEngine(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "isready" {
+			// delays the readyok answer
+			time.Sleep(200 * time.Millisecond)
+		}
+		return nil
+	})
+	engine.ReadTimeout = 50 * time.Millisecond
+
+	err := engine.IsReady()
+	if !errors.Is(err, ErrReadTimeout) {
+		t.Errorf("IsReady() on a slow engine: expected %v, actual %v", ErrReadTimeout, err)
+	}
+
+	engine.ReadTimeout = time.Second
+	err = engine.IsReady()
+	if err != nil {
+		t.Errorf("IsReady() with a sufficient ReadTimeout: %s", err)
+	}
+}
+
+func TestParseSANMove(t *testing.T) {
+	engine, _ := newFakeEngine(nil)
+	engine.SetPosition([]string{"e2e4", "e7e5"})
+
+	var tests = []struct {
+		san      string
+		expected string
+		err      string
+	}{
+		{"Nf3", "g1f3", ""},
+		{"Qh5", "d1h5", ""},
+		{"Bc4+", "f1c4", ""},
+		{"O-O", "", "Illegal move O-O"},
+		{"Ke3", "", "Illegal move Ke3"},
+		{"e5", "", "Illegal move e5"},
+		{"Nac3", "", "No such piece for move Nac3"},
+		{"Xe4", "", "Could not parse SAN move Xe4"},
+	}
+	for _, tt := range tests {
+		actual, err := engine.ParseSANMove(tt.san)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("ParseSANMove(\"%s\"): expected error %s, actual %v", tt.san, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if actual != tt.expected {
+			t.Errorf("ParseSANMove(\"%s\"): expected %s, actual %s", tt.san, tt.expected, actual)
+		}
+	}
+
+	engine.SetFENPosition("4k3/8/8/8/8/5N2/8/1N2K3 w - - 0 1")
+	_, err := engine.ParseSANMove("Nd2")
+	if err == nil || err.Error() != "Ambiguous move Nd2" {
+		t.Errorf("ParseSANMove(\"Nd2\"): expected error %s, actual %v", "Ambiguous move Nd2", err)
+	}
+	actual, err := engine.ParseSANMove("Nfd2")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if actual != "f3d2" {
+		t.Errorf("ParseSANMove(\"Nfd2\"): expected %s, actual %s", "f3d2", actual)
+	}
+}
+
+func TestRecentTraffic(t *testing.T) {
+	engine, _ := newFakeEngine(nil)
+	engine.IsReady()
+	if traffic := engine.RecentTraffic(); len(traffic) != 0 {
+		t.Errorf("RecentTraffic() with TrafficSize 0: expected no lines, actual %v", traffic)
+	}
+
+	engine.TrafficSize = 3
+	engine.IsReady()
+	expected := []string{"> isready", "< readyok"}
+	if traffic := engine.RecentTraffic(); !reflect.DeepEqual(traffic, expected) {
+		t.Errorf("RecentTraffic(): expected %v, actual %v", expected, traffic)
+	}
+
+	engine.Put("ucinewgame")
+	engine.IsReady()
+	expected = []string{"> ucinewgame", "> isready", "< readyok"}
+	if traffic := engine.RecentTraffic(); !reflect.DeepEqual(traffic, expected) {
+		t.Errorf("RecentTraffic(): expected %v, actual %v", expected, traffic)
+	}
+}
+
+func TestOptionOrder(t *testing.T) {
+	engine, fake := newFakeEngine(nil)
+	engine.Param = map[string]string{
+		"Contempt":              "0",
+		"Hash":                  "256",
+		"Threads":               "4",
+		"Skill Level":           "20",
+		"Minimum Thinking Time": "20",
+	}
+	err := engine.applyParam()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	var actual []string
+	for _, command := range fake.sent() {
+		if strings.HasPrefix(command, "setoption ") && !strings.Contains(command, "name Ponder ") {
+			actual = append(actual, command)
+		}
+	}
+	expected := []string{
+		"setoption name Threads value 4",
+		"setoption name Hash value 256",
+		"setoption name Contempt value 0",
+		"setoption name Minimum Thinking Time value 20",
+		"setoption name Skill Level value 20",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("applyParam(): expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestAnalyzeStable(t *testing.T) {
+	infos := func(moves ...string) []string {
+		var lines []string
+		for i, move := range moves {
+			lines = append(lines, fmt.Sprintf("info depth %d seldepth %d multipv 1 score cp 30 nodes %d nps 1000 tbhits 0 time 1 pv %s", i+1, i+1, 100*(i+1), move))
+		}
+		return lines
+	}
+	var tests = []struct {
+		moves    []string
+		stable   int
+		expected string
+		depth    int
+		stopped  bool
+	}{
+		{[]string{"d2d4", "e2e4", "d2d4", "d2d4", "d2d4", "c2c4", "e2e4", "e2e4"}, 3, "d2d4", 3, true},
+		{[]string{"d2d4", "e2e4", "d2d4", "d2d4", "c2c4", "c2c4", "c2c4", "c2c4"}, 3, "c2c4", 5, true},
+		{[]string{"d2d4", "e2e4", "d2d4", "d2d4", "c2c4", "e2e4", "d2d4", "d2d4"}, 3, "d2d4", 0, false},
+		{[]string{"d2d4", "e2e4", "d2d4", "c2c4", "d2d4", "e2e4", "g1f3", "g1f3"}, 2, "g1f3", 7, false},
+	}
+	for _, tt := range tests {
+		moves := tt.moves
+		engine, fake := newFakeEngine(func(command string) []string {
+			if command == "go depth 8" {
+				return append(infos(moves...), "bestmove "+moves[len(moves)-1])
+			}
+			return nil
+		})
+		move, depth, err := engine.AnalyzeStable(StartFEN, 8, tt.stable)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if move != tt.expected || depth != tt.depth {
+			t.Errorf("AnalyzeStable(%v, %d): expected %s at depth %d, actual %s at depth %d", tt.moves, tt.stable, tt.expected, tt.depth, move, depth)
+		}
+		stopped := false
+		for _, command := range fake.sent() {
+			stopped = stopped || command == "stop"
+		}
+		if stopped != tt.stopped {
+			t.Errorf("AnalyzeStable(%v, %d): expected stopped %v, actual %v", tt.moves, tt.stable, tt.stopped, stopped)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	engine, err := NewEngineWithAllOptions(writeFakeExecutable(t, fakeExecutable), 4, false, map[string]string{"Hash": "32"}, false, -10, 10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = engine.Close()
+	if err != nil {
+		t.Errorf("Close(): %s", err)
+	}
+	if engine.IsAlive() {
+		t.Errorf("IsAlive(): expected false after Close")
+	}
+	err = engine.Close()
+	if err != nil {
+		t.Errorf("Close() twice: %s", err)
+	}
+
+	err = engine.Restart()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !engine.IsAlive() {
+		t.Errorf("IsAlive(): expected true after Restart")
+	}
+	err = engine.Close()
+	if err != nil {
+		t.Errorf("Close() after Restart: %s", err)
+	}
+
+	fake, _ := newFakeEngine(nil)
+	if err := fake.Close(); err != nil {
+		t.Errorf("Close() without a process: %s", err)
+	}
+	if err := fake.Close(); err != nil {
+		t.Errorf("Close() twice without a process: %s", err)
+	}
+}
+
+func TestMovetime(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go movetime 100" {
+			return []string{
+				"info depth 5 seldepth 6 multipv 1 score cp 25 nodes 5000 nps 50000 tbhits 0 time 100 pv d2d4 d7d5",
+				"bestmove d2d4 ponder d7d5",
+			}
+		}
+		return nil
+	})
+	engine.SetPosition(nil)
+
+	bestMove, err := engine.BestMoveMovetime(100)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "d2d4" || bestMove.Ponder != "d7d5" || bestMove.Info == nil || bestMove.Info.Time != 100 {
+		t.Errorf("BestMoveMovetime(100): unexpected %+v (info %+v)", bestMove, bestMove.Info)
+	}
+
+	if _, err := engine.BestMoveMovetime(0); err == nil {
+		t.Errorf("BestMoveMovetime(0): expected error")
+	}
+	if err := engine.GoMovetime(-5); err == nil {
+		t.Errorf("GoMovetime(-5): expected error")
+	}
+	if err := engine.GoMovetime(100); err != nil {
+		t.Errorf("GoMovetime(100): %s", err)
+	}
+	sent := fake.sent()
+	if sent[len(sent)-1] != "go movetime 100" {
+		t.Errorf("GoMovetime(100): expected %s as last command, actual %s", "go movetime 100", sent[len(sent)-1])
+	}
+	// the search ended with its bestmove before 'readyok' could answer a fence
+	bestMove, err = engine.Stop()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "d2d4" {
+		t.Errorf("Stop() after GoMovetime(100): expected d2d4, actual %s", bestMove.Move)
+	}
+	if err := engine.IsReady(); err != nil {
+		t.Errorf("IsReady() after GoMovetime(100): %s", err)
+	}
+}
+
+func TestGoInfinite(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		switch command {
+		case "go infinite":
+			return []string{
+				"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"info depth 2 seldepth 2 multipv 1 score cp 35 nodes 60 nps 30000 tbhits 0 time 2 pv d2d4 d7d5",
+			}
+		case "stop":
+			return []string{"bestmove d2d4 ponder d7d5"}
+		}
+		return nil
+	})
+	engine.SetPosition(nil)
+
+	err := engine.GoInfinite()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	sent := fake.sent()
+	if sent[len(sent)-1] != "go infinite" {
+		t.Errorf("GoInfinite(): expected %s as last command, actual %s", "go infinite", sent[len(sent)-1])
+	}
+
+	bestMove, err := engine.Stop()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "d2d4" || bestMove.Info == nil || bestMove.Info.Depth != 2 {
+		t.Errorf("Stop(): unexpected %+v (info %+v)", bestMove, bestMove.Info)
+	}
+	if err := engine.IsReady(); err != nil {
+		t.Errorf("IsReady() after Stop: %s", err)
+	}
+}
+
+func TestParseInfoCurrMove(t *testing.T) {
+	var tests = []struct {
+		line     string
+		expected *Info
+	}{
+		{"info depth 20 currmove e2e4 currmovenumber 1", &Info{Depth: 20, CurrMove: "e2e4", CurrMoveNumber: 1}},
+		{"info depth 24 currmove e7e8q currmovenumber 12", &Info{Depth: 24, CurrMove: "e7e8q", CurrMoveNumber: 12}},
+		{"info currmove g1f3", &Info{CurrMove: "g1f3"}},
+	}
+	for _, tt := range tests {
+		actual, err := ParseInfo(tt.line)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("ParseInfo(\"%s\"): expected %+v, actual %+v", tt.line, tt.expected, actual)
+		}
+		if !actual.IsCurrMove() {
+			t.Errorf("ParseInfo(\"%s\").IsCurrMove(): expected true", tt.line)
+		}
+	}
+
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{
+				"info depth 2 seldepth 2 multipv 1 score cp 35 nodes 60 nps 30000 tbhits 0 time 2 pv d2d4 d7d5",
+				"info depth 3 currmove e2e4 currmovenumber 2",
+				"bestmove d2d4 ponder d7d5",
+			}
+		}
+		return nil
+	})
+	var progress []string
+	bestMove, err := engine.BestMoveWithProgress(func(info *Info) {
+		if info.IsCurrMove() {
+			progress = append(progress, info.CurrMove)
+		}
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Info == nil || bestMove.Info.Pv != "d2d4 d7d5" {
+		t.Errorf("BestMoveWithProgress(): expected the last info with a pv, actual %+v", bestMove.Info)
+	}
+	if !reflect.DeepEqual(progress, []string{"e2e4"}) {
+		t.Errorf("BestMoveWithProgress(): expected currmove e2e4, actual %v", progress)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{
+				"info depth 1 seldepth 1 multipv 1 score cp 30 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"info depth 1 seldepth 1 multipv 2 score cp 25 nodes 40 nps 20000 tbhits 0 time 2 pv d2d4",
+				"info depth 1 seldepth 1 multipv 3 score cp 10 nodes 60 nps 20000 tbhits 0 time 3 pv g1f3",
+				"info depth 2 seldepth 2 multipv 1 score cp 45 lowerbound nodes 80 nps 20000 tbhits 0 time 4 pv d2d4",
+				"info depth 2 seldepth 2 multipv 1 score cp 40 nodes 100 nps 20000 tbhits 0 time 5 pv d2d4 d7d5",
+				"info depth 2 seldepth 2 multipv 2 score cp 35 nodes 120 nps 20000 tbhits 0 time 6 pv e2e4 e7e5",
+				"info depth 2 seldepth 2 multipv 3 score cp 5 nodes 140 nps 20000 tbhits 0 time 7 pv c2c4 e7e5",
+				"info depth 3 seldepth 3 multipv 1 score cp 38 nodes 160 nps 20000 tbhits 0 time 8 pv d2d4 d7d5 c2c4",
+				"bestmove d2d4 ponder d7d5",
+			}
+		}
+		return nil
+	})
+	infos, err := engine.Analyze()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{"d2d4 d7d5", "e2e4 e7e5", "c2c4 e7e5"}
+	var actual []string
+	for i, info := range infos {
+		actual = append(actual, info.Pv)
+		if info.Depth != 2 || info.Multipv != i+1 {
+			t.Errorf("Analyze(): expected depth 2 multipv %d, actual depth %d multipv %d", i+1, info.Depth, info.Multipv)
+		}
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Analyze(): expected %v, actual %v", expected, actual)
+	}
+	if infos[0].Score.Bound != "" {
+		t.Errorf("Analyze(): expected the exact score to replace the bound, actual %+v", infos[0].Score)
+	}
+}
+
+func TestGetFEN(t *testing.T) {
+	engine, fake := newFakeEngine(stockfishDisplay)
+
+	fen, err := engine.GetFEN()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := "r1bqkb1r/pppp1Qpp/2n2n2/4p3/2B1P3/8/PPPP1PPP/RNB1K1NR b KQkq - 0 4"
+	if fen != expected {
+		t.Errorf("GetFEN(): expected %s, actual %s", expected, fen)
+	}
+	if sent := fake.sent(); !reflect.DeepEqual(sent, []string{"d", "isready"}) {
+		t.Errorf("GetFEN(): expected d and isready, actual %v", sent)
+	}
+	err = engine.IsReady()
+	if err != nil {
+		t.Errorf("IsReady() after GetFEN: %s", err)
+	}
+
+	engine, _ = newFakeEngine(func(command string) []string {
+		if command == "d" {
+			return []string{"Unknown command: d"}
+		}
+		return nil
+	})
+	_, err = engine.GetFEN()
+	if err == nil {
+		t.Errorf("GetFEN(): expected error for an engine without 'd'")
+	}
+}
+
+func TestSetEloLimit(t *testing.T) {
+	engine, fake := newFakeEngine(nil)
+
+	for _, elo := range []int{1000, MinElo - 1, MaxElo + 1} {
+		if err := engine.SetEloLimit(elo); err == nil {
+			t.Errorf("SetEloLimit(%d): expected error", elo)
+		}
+	}
+	if sent := fake.sent(); len(sent) != 0 {
+		t.Errorf("SetEloLimit with invalid Elo: expected no commands, actual %v", sent)
+	}
+
+	err := engine.SetEloLimit(1500)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if engine.Param["UCI_LimitStrength"] != "true" || engine.Param["UCI_Elo"] != "1500" {
+		t.Errorf("SetEloLimit(1500): expected the limit in Param, actual %v", engine.Param)
+	}
+	err = engine.DisableEloLimit()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, ok := engine.Param["UCI_Elo"]; ok || engine.Param["UCI_LimitStrength"] != "false" {
+		t.Errorf("DisableEloLimit(): expected no limit in Param, actual %v", engine.Param)
+	}
+
+	expected := []string{
+		"setoption name UCI_LimitStrength value true",
+		"isready",
+		"setoption name UCI_Elo value 1500",
+		"isready",
+		"setoption name UCI_LimitStrength value false",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SetEloLimit(1500), DisableEloLimit(): expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestSetSyzygyPath(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		switch command {
+		case "setoption name SyzygyPath value /tb/wdl:/tb/dtz":
+			return []string{"info string Found 145 WDL and 145 DTZ tablebase files (up to 5-man)."}
+		case "setoption name SyzygyPath value /tmp":
+			return []string{"info string Found 0 WDL and 0 DTZ tablebase files (up to 0-man)."}
+		case "setoption name SyzygyPath value /old":
+			return []string{"info string Found 510 tablebases"}
+		}
+		return nil
+	})
+
+	for _, path := range []string{"/tb/wdl:/tb/dtz", "/old", ""} {
+		if err := engine.SetSyzygyPath(path); err != nil {
+			t.Errorf("SetSyzygyPath(\"%s\"): %s", path, err)
+		}
+	}
+	if engine.Param["SyzygyPath"] != "<empty>" {
+		t.Errorf("SetSyzygyPath(\"\"): expected %s in Param, actual %s", "<empty>", engine.Param["SyzygyPath"])
+	}
+	for _, path := range []string{"/tmp", "/silent"} {
+		if err := engine.SetSyzygyPath(path); err == nil {
+			t.Errorf("SetSyzygyPath(\"%s\"): expected error", path)
+		}
+	}
+
+	if err := engine.SetSyzygyProbeDepth(0); err == nil {
+		t.Errorf("SetSyzygyProbeDepth(0): expected error")
+	}
+	if err := engine.SetSyzygyProbeLimit(8); err == nil {
+		t.Errorf("SetSyzygyProbeLimit(8): expected error")
+	}
+	if err := engine.SetSyzygyProbeDepth(4); err != nil {
+		t.Errorf("SetSyzygyProbeDepth(4): %s", err)
+	}
+	if err := engine.SetSyzygyProbeLimit(5); err != nil {
+		t.Errorf("SetSyzygyProbeLimit(5): %s", err)
+	}
+	sent := fake.sent()
+	expected := []string{"setoption name SyzygyProbeDepth value 4", "isready", "setoption name SyzygyProbeLimit value 5", "isready"}
+	if actual := sent[len(sent)-4:]; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SetSyzygyProbeDepth(4), SetSyzygyProbeLimit(5): expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "uci" {
+			return []string{
+				"id name Stockfish 16",
+				"option name Hash type spin default 16 min 1 max 33554432",
+				"option name Analysis Contempt type combo default Both var Off var White var Black var Both",
+				"option name UCI_Elo type spin default 1320 min 1320 max 3190",
+				"uciok",
+			}
+		}
+		return nil
+	})
+	engine.Put("uci")
+	err := engine.waitForUCIOK()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	options := engine.Options()
+	if len(options) != 3 {
+		t.Errorf("Options(): expected 3 options, actual %v", options)
+	}
+	if hash := options["Hash"]; hash.Type != "spin" || hash.Default != "16" || hash.Min != 1 || hash.Max != 33554432 {
+		t.Errorf("Options(): unexpected Hash %+v", hash)
+	}
+	options["Analysis Contempt"].Var[0] = "changed"
+	if engine.Options()["Analysis Contempt"].Var[0] != "Off" {
+		t.Errorf("Options(): expected a copy of the options")
+	}
+
+	var tests = []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"Hash", "64", true},
+		{"Hash", "0", false},
+		{"Analysis Contempt", "white", true},
+		{"Analysis Contempt", "Both sides", false},
+	}
+	for _, tt := range tests {
+		err := engine.validateOption(tt.name, tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateOption(\"%s\", \"%s\"): expected valid %v, actual %v", tt.name, tt.value, tt.valid, err)
+		}
+	}
+
+	if err := engine.SetEloLimit(1320); err != nil {
+		t.Errorf("SetEloLimit(1320): %s", err)
+	}
+	if err := engine.SetEloLimit(3200); err == nil {
+		t.Errorf("SetEloLimit(3200): expected error for the advertised range")
+	}
+}
+
+func TestPonderHit(t *testing.T) {
+	pondering := false
+	engine, fake := newFakeEngine(func(command string) []string {
+		switch {
+		case command == "go depth 2":
+			return []string{
+				"info depth 2 seldepth 2 multipv 1 score cp 30 nodes 40 nps 40000 tbhits 0 time 1 pv e2e4 e7e5",
+				"bestmove e2e4 ponder e7e5",
+			}
+		case command == "go ponder depth 2":
+			pondering = true
+			return []string{"info depth 1 seldepth 1 multipv 1 score cp 25 nodes 20 nps 20000 tbhits 0 time 1 pv g1f3"}
+		case command == "ponderhit" && pondering:
+			return []string{
+				"info depth 2 seldepth 3 multipv 1 score cp 28 nodes 60 nps 30000 tbhits 0 time 2 pv g1f3 b8c6",
+				"bestmove g1f3 ponder b8c6",
+			}
+		case command == "stop" && pondering:
+			return []string{"bestmove g1f3 ponder b8c6"}
+		}
+		return nil
+	})
+	engine.Ponder = true
+	engine.SetPosition(nil)
+
+	bestMove, err := engine.BestMove()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = engine.StartPondering(bestMove)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if expected := "position fen " + StartFEN + " moves e2e4 e7e5"; engine.position != expected {
+		t.Errorf("StartPondering(): expected %s, actual %s", expected, engine.position)
+	}
+	bestMove, err = engine.PonderHit()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "g1f3" || bestMove.Info == nil || bestMove.Info.Depth != 2 {
+		t.Errorf("PonderHit(): unexpected %+v (info %+v)", bestMove, bestMove.Info)
+	}
+	if bestMove.SideToMove != ColorWhite {
+		t.Errorf("PonderHit(): expected side to move %s, actual %s", ColorWhite, bestMove.SideToMove)
+	}
+
+	err = engine.StartPondering(bestMove)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	err = engine.StopPonder()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := engine.IsReady(); err != nil {
+		t.Errorf("IsReady() after StopPonder: %s", err)
+	}
+	if err := engine.StartPondering(&BestMove{Move: "e2e4"}); err == nil {
+		t.Errorf("StartPondering() without ponder move: expected error")
+	}
+
+	var ponders int
+	for _, command := range fake.sent() {
+		if command == "go ponder depth 2" {
+			ponders++
+		}
+	}
+	if ponders != 2 {
+		t.Errorf("StartPondering(): expected 2 ponder searches, actual %d", ponders)
+	}
+}
+
+func TestBestMoveSearchMoves(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go depth 2 searchmoves d2d4 c2c4 g1f3" {
+			return []string{
+				"info depth 2 seldepth 2 multipv 1 score cp 30 nodes 40 nps 40000 tbhits 0 time 1 pv d2d4 d7d5",
+				"bestmove d2d4 ponder d7d5",
+			}
+		}
+		return nil
+	})
+
+	bestMove, err := engine.BestMoveSearchMoves([]string{"d2d4", "c2c4", "g1f3"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "d2d4" {
+		t.Errorf("BestMoveSearchMoves(): expected d2d4, actual %s", bestMove.Move)
+	}
+
+	_, err = engine.BestMoveSearchMoves([]string{"d2d4", "Nf3", "e7e8k"})
+	if err == nil || err.Error() != "Could not parse search moves: Nf3, e7e8k" {
+		t.Errorf("BestMoveSearchMoves() with malformed moves: unexpected error %v", err)
+	}
+	if _, err := engine.BestMoveSearchMoves(nil); err == nil {
+		t.Errorf("BestMoveSearchMoves(nil): expected error")
+	}
+	if sent := fake.sent(); len(sent) != 1 {
+		t.Errorf("BestMoveSearchMoves(): expected only the valid search to be sent, actual %v", sent)
+	}
+}
+
+func TestNodes(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go nodes 5000" {
+			return []string{
+				"info depth 6 seldepth 8 multipv 1 score cp 22 nodes 5000 nps 500000 tbhits 0 time 10 pv e2e4 e7e5",
+				"bestmove e2e4 ponder e7e5",
+			}
+		}
+		return nil
+	})
+	engine.SetPosition(nil)
+
+	bestMove, err := engine.BestMoveNodes(5000)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "e2e4" || bestMove.Info == nil || bestMove.Info.Nodes != 5000 {
+		t.Errorf("BestMoveNodes(5000): unexpected %+v (info %+v)", bestMove, bestMove.Info)
+	}
+
+	if _, err := engine.BestMoveNodes(0); err == nil {
+		t.Errorf("BestMoveNodes(0): expected error")
+	}
+	if err := engine.GoNodes(-1); err == nil {
+		t.Errorf("GoNodes(-1): expected error")
+	}
+	if err := engine.GoNodes(5000); err != nil {
+		t.Errorf("GoNodes(5000): %s", err)
+	}
+	sent := fake.sent()
+	if sent[len(sent)-1] != "go nodes 5000" {
+		t.Errorf("GoNodes(5000): expected %s as last command, actual %s", "go nodes 5000", sent[len(sent)-1])
+	}
+	// the search ended with its bestmove before 'readyok' could answer a fence
+	bestMove, err = engine.Stop()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "e2e4" {
+		t.Errorf("Stop() after GoNodes(5000): expected e2e4, actual %s", bestMove.Move)
+	}
+	if err := engine.IsReady(); err != nil {
+		t.Errorf("IsReady() after GoNodes(5000): %s", err)
+	}
+}
+
+func TestGoMate(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		switch command {
+		case "go mate 2":
+			return []string{
+				"info depth 3 seldepth 4 multipv 1 score mate 2 nodes 900 nps 90000 tbhits 0 time 10 pv d1h5 g8f6 h5f7",
+				"bestmove d1h5 ponder g8f6",
+			}
+		case "go mate 1":
+			return []string{
+				"info depth 2 seldepth 2 multipv 1 score cp 250 nodes 300 nps 30000 tbhits 0 time 10 pv d1h5 g8f6",
+				"bestmove d1h5 ponder g8f6",
+			}
+		case "go mate 3":
+			return []string{
+				"info depth 5 seldepth 6 multipv 1 score mate -4 nodes 300 nps 30000 tbhits 0 time 10 pv d1h5 g8f6",
+				"bestmove d1h5 ponder g8f6",
+			}
+		}
+		return nil
+	})
+
+	bestMove, err := engine.GoMate(2)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if bestMove.Move != "d1h5" || bestMove.Info.Score != (Score{Eval: "mate", Value: 2}) {
+		t.Errorf("GoMate(2): unexpected %+v (info %+v)", bestMove, bestMove.Info)
+	}
+	for _, n := range []int{1, 3} {
+		bestMove, err = engine.GoMate(n)
+		if err != ErrNoMate {
+			t.Errorf("GoMate(%d): expected %v, actual %v", n, ErrNoMate, err)
+		}
+		if bestMove == nil || bestMove.Move != "d1h5" {
+			t.Errorf("GoMate(%d): expected the best move with ErrNoMate, actual %+v", n, bestMove)
+		}
+	}
+	if _, err := engine.GoMate(0); err == nil || err == ErrNoMate {
+		t.Errorf("GoMate(0): expected an invalid argument error, actual %v", err)
+	}
+}
+
+// newStalledFakeEngine is like newFakeEngine but does not answer 'isready' until release is
+// called. The 'readyok' held back are sent before the answer to the next command.
+func newStalledFakeEngine(respond func(command string) []string) (engine *Engine, release func()) {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	output := make(chan string, 4096)
+	var mu sync.Mutex
+	released := false
+
+	go func() {
+		scanner := bufio.NewScanner(inReader)
+		held := 0
+		for scanner.Scan() {
+			command := scanner.Text()
+			mu.Lock()
+			stalled := !released
+			mu.Unlock()
+			for ; !stalled && held > 0; held-- {
+				output <- "readyok"
+			}
+			for _, line := range respond(command) {
+				output <- line
+			}
+			if command == "isready" {
+				if stalled {
+					held++
+				} else {
+					output <- "readyok"
+				}
+			}
+		}
+		close(output)
+	}()
+	go func() {
+		for line := range output {
+			io.WriteString(outWriter, line+"\n")
+		}
+		outWriter.Close()
+	}()
+
+	var stdin io.WriteCloser = inWriter
+	engine = &Engine{
+		Executable: "fake",
+		Stdin:      &stdin,
+		Stdout:     bufio.NewReader(outReader),
+		Depth:      2,
+		Param:      map[string]string{},
+	}
+	release = func() {
+		mu.Lock()
+		released = true
+		mu.Unlock()
+	}
+	return engine, release
+}
+
+func TestIsReadyTimeout(t *testing.T) {
+	engine, release := newStalledFakeEngine(func(command string) []string {
+		if command == "setoption name Foo value 1" {
+			return []string{"No such option: Foo"}
+		}
+		return nil
+	})
+
+	err := engine.IsReadyTimeout(50 * time.Millisecond)
+	if !errors.Is(err, ErrNotReady) {
+		t.Fatalf("IsReadyTimeout(50ms) on a slow engine: expected %v, actual %v", ErrNotReady, err)
+	}
+	if expected := "Engine not ready after 50ms"; err.Error() != expected {
+		t.Errorf("IsReadyTimeout(50ms): expected error %s, actual %s", expected, err)
+	}
+
+	defer func(timeout time.Duration) {
+		ReadyTimeout = timeout
+	}(ReadyTimeout)
+	ReadyTimeout = 50 * time.Millisecond
+	err = engine.IsReady()
+	if !errors.Is(err, ErrNotReady) {
+		t.Errorf("IsReady() with ReadyTimeout on a slow engine: expected %v, actual %v", ErrNotReady, err)
+	}
+
+	// the engine answers again, the late 'readyok' of both fences must not answer the next ones
+	release()
+	ReadyTimeout = 5 * time.Second
+	err = engine.SetOption("Foo", "1")
+	if _, ok := err.(*CommandError); !ok {
+		t.Errorf("SetOption(\"Foo\", \"1\") after a timeout: expected *CommandError, actual %v", err)
+	}
+	err = engine.IsReadyTimeout(time.Second)
+	if err != nil {
+		t.Errorf("IsReadyTimeout(1s) after a timeout: %s", err)
+	}
 }
@@ -0,0 +1,177 @@
+package gostockfish
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// epdRegex splits an EPD line into the four position fields and the operations
+var epdRegex = regexp.MustCompile(`^\s*(\S+\s+\S+\s+\S+\s+\S+)\s*(.*)$`)
+
+// EPD is a position in Extended Position Description notation as used by test suites, i.e.
+// "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - bm Qxf7#; id \"mate in 1\";"
+type EPD struct {
+	FEN        string
+	Operations map[string][]string
+	BestMoves  []string
+	AvoidMoves []string
+	ID         string
+}
+
+// SuiteResult describes the outcome of a single test suite position
+type SuiteResult struct {
+	EPD      *EPD
+	BestMove *BestMove
+	Passed   bool
+	Reason   string
+}
+
+// ParseEPD parses a single EPD line. The halfmove clock and fullmove number of FEN are taken
+// from the 'hmvc' and 'fmvn' operations, if present.
+func ParseEPD(line string) (*EPD, error) {
+	matches := epdRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, fmt.Errorf("Could not parse EPD: %s", line)
+	}
+	position := matches[1]
+
+	epd := &EPD{
+		Operations: map[string][]string{},
+	}
+	for _, operation := range splitEPDOperations(matches[2]) {
+		if len(operation) == 0 {
+			continue
+		}
+		epd.Operations[operation[0]] = operation[1:]
+	}
+
+	halfmove, fullmove := "0", "1"
+	if operands := epd.Operations["hmvc"]; len(operands) == 1 {
+		halfmove = operands[0]
+	}
+	if operands := epd.Operations["fmvn"]; len(operands) == 1 {
+		fullmove = operands[0]
+	}
+	epd.FEN = fmt.Sprintf("%s %s %s", position, halfmove, fullmove)
+	_, err := ParseFEN(epd.FEN)
+	if err != nil {
+		return nil, err
+	}
+
+	epd.BestMoves = epd.Operations["bm"]
+	epd.AvoidMoves = epd.Operations["am"]
+	if operands := epd.Operations["id"]; len(operands) > 0 {
+		epd.ID = strings.Join(operands, " ")
+	}
+
+	return epd, nil
+}
+
+// splitEPDOperations splits the operations of an EPD line into opcode and operands.
+// Operations end with ';', operands in double quotes may contain spaces and semicolons.
+func splitEPDOperations(operations string) [][]string {
+	var result [][]string
+	var operation []string
+	var token strings.Builder
+	quoted := false
+
+	endToken := func() {
+		if token.Len() > 0 {
+			operation = append(operation, token.String())
+			token.Reset()
+		}
+	}
+
+	for _, c := range operations {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			token.WriteRune(c)
+		case c == ';':
+			endToken()
+			result = append(result, operation)
+			operation = nil
+		case c == ' ' || c == '\t':
+			endToken()
+		default:
+			token.WriteRune(c)
+		}
+	}
+	endToken()
+	if len(operation) > 0 {
+		result = append(result, operation)
+	}
+	return result
+}
+
+// RunSuite searches every position of a test suite and checks the engine's best move against
+// the 'bm' (best move) and 'am' (avoid move) operations. A position is passed if the best move
+// is one of the bm moves and none of the am moves.
+func (engine *Engine) RunSuite(positions []*EPD) ([]*SuiteResult, error) {
+	var results []*SuiteResult
+
+	for _, epd := range positions {
+		err := engine.SetFENPosition(epd.FEN)
+		if err != nil {
+			return nil, err
+		}
+		bestMove, err := engine.BestMove()
+		if err != nil {
+			return nil, err
+		}
+		result, err := epd.check(bestMove)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// check scores the engine's best move against the bm and am operations
+func (epd *EPD) check(bestMove *BestMove) (*SuiteResult, error) {
+	board, err := ParseFEN(epd.FEN)
+	if err != nil {
+		return nil, err
+	}
+	toUCI := func(sans []string) (map[string]bool, error) {
+		moves := map[string]bool{}
+		for _, san := range sans {
+			uci, err := board.SANToUCI(san)
+			if err != nil {
+				return nil, fmt.Errorf("Could not parse move %s of %s: %s", san, epd.ID, err.Error())
+			}
+			moves[uci] = true
+		}
+		return moves, nil
+	}
+	best, err := toUCI(epd.BestMoves)
+	if err != nil {
+		return nil, err
+	}
+	avoid, err := toUCI(epd.AvoidMoves)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SuiteResult{
+		EPD:      epd,
+		BestMove: bestMove,
+		Passed:   true,
+	}
+	if len(best) > 0 && !best[bestMove.Move] {
+		result.Passed = false
+		result.Reason = fmt.Sprintf("%s is not a best move (bm %s)", bestMove.Move, strings.Join(epd.BestMoves, " "))
+	} else if avoid[bestMove.Move] {
+		result.Passed = false
+		result.Reason = fmt.Sprintf("%s should be avoided (am %s)", bestMove.Move, strings.Join(epd.AvoidMoves, " "))
+	} else if len(best) > 0 {
+		result.Reason = fmt.Sprintf("%s is a best move (bm %s)", bestMove.Move, strings.Join(epd.BestMoves, " "))
+	} else {
+		result.Reason = fmt.Sprintf("%s is not avoided (am %s)", bestMove.Move, strings.Join(epd.AvoidMoves, " "))
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package gostockfish
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEPD(t *testing.T) {
+	epd, err := ParseEPD(`r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - bm Qxf7#; id "scholar's mate; 1";`)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := &EPD{
+		FEN: "r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 0 1",
+		Operations: map[string][]string{
+			"bm": {"Qxf7#"},
+			"id": {"scholar's mate; 1"},
+		},
+		BestMoves: []string{"Qxf7#"},
+		ID:        "scholar's mate; 1",
+	}
+	if !reflect.DeepEqual(epd, expected) {
+		t.Errorf("ParseEPD: expected %v, actual %v", expected, epd)
+	}
+}
+
+func TestRunSuite(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if strings.HasPrefix(command, "go ") {
+			return []string{"info depth 2 seldepth 2 multipv 1 score cp 30 nodes 400 nps 40000 tbhits 0 time 10 pv e2e4", "bestmove e2e4"}
+		}
+		return nil
+	})
+
+	var lines = []string{
+		`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm d4 e4; id "bm two moves";`,
+		`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - bm Nf3; id "bm missed";`,
+		`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - am f3; id "am passed";`,
+		`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - am e4; id "am failed";`,
+	}
+	expected := []bool{true, false, true, false}
+
+	var positions []*EPD
+	for _, line := range lines {
+		epd, err := ParseEPD(line)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		positions = append(positions, epd)
+	}
+
+	results, err := engine.RunSuite(positions)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, result := range results {
+		if result.Passed != expected[i] {
+			t.Errorf("RunSuite: %s expected passed %v, actual %v (%s)", result.EPD.ID, expected[i], result.Passed, result.Reason)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package gostockfish
+
+import (
+	"fmt"
+)
+
+// ICCFToUCI converts a move in ICCF numeric notation as used in correspondence chess (i.e.
+// '5254' for e2e4) to full algebraic notation. Files and ranks are numbered 1 to 8, the
+// optional fifth digit is the promotion piece: 1 queen, 2 rook, 3 bishop, 4 knight (i.e.
+// '57584' for e7e8n).
+func ICCFToUCI(move string) (string, error) {
+	if len(move) != 4 && len(move) != 5 {
+		return "", fmt.Errorf("Could not parse ICCF move: %s", move)
+	}
+	uci := make([]byte, 0, 5)
+	for i := 0; i < 4; i++ {
+		if move[i] < '1' || move[i] > '8' {
+			return "", fmt.Errorf("Could not parse ICCF move: %s", move)
+		}
+		if i%2 == 0 {
+			uci = append(uci, 'a'+move[i]-'1')
+		} else {
+			uci = append(uci, move[i])
+		}
+	}
+	if len(move) == 5 {
+		if move[4] < '1' || move[4] > '4' {
+			return "", fmt.Errorf("Could not parse ICCF move, invalid promotion piece: %s", move)
+		}
+		uci = append(uci, "qrbn"[move[4]-'1'])
+	}
+	return string(uci), nil
+}
+
+// SetPositionICCF sets the position reached by playing the list of moves in ICCF numeric
+// notation (i.e. ['5254', '5755', ...]) from the starting position
+func (engine *Engine) SetPositionICCF(moves []string) error {
+	var uciMoves []string
+	for _, move := range moves {
+		uci, err := ICCFToUCI(move)
+		if err != nil {
+			return err
+		}
+		uciMoves = append(uciMoves, uci)
+	}
+	return engine.SetPosition(uciMoves)
+}
@@ -0,0 +1,51 @@
+package gostockfish
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestICCFToUCI(t *testing.T) {
+	var tests = []struct {
+		input    string
+		expected string
+	}{
+		{"5254", "e2e4"},
+		{"7163", "g1f3"},
+		{"1878", "a8g8"},
+		{"57581", "e7e8q"},
+		{"57582", "e7e8r"},
+		{"27183", "b7a8b"},
+		{"42414", "d2d1n"},
+	}
+	for _, tt := range tests {
+		actual, err := ICCFToUCI(tt.input)
+		if err != nil {
+			t.Errorf("ICCFToUCI(\"%s\"): %s", tt.input, err)
+		} else if actual != tt.expected {
+			t.Errorf("ICCFToUCI(\"%s\"): expected %s, actual %s", tt.input, tt.expected, actual)
+		}
+	}
+
+	for _, input := range []string{"", "525", "5294", "0254", "e2e4", "57585", "525411"} {
+		if _, err := ICCFToUCI(input); err == nil {
+			t.Errorf("ICCFToUCI(\"%s\"): expected error", input)
+		}
+	}
+}
+
+func TestSetPositionICCF(t *testing.T) {
+	engine, fake := newFakeEngine(nil)
+	err := engine.SetPositionICCF([]string{"5254", "5755"})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{"position startpos moves e2e4 e7e5", "isready"}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SetPositionICCF: expected %v, actual %v", expected, actual)
+	}
+
+	if err := engine.SetPositionICCF([]string{"5254", "57"}); err == nil {
+		t.Errorf("SetPositionICCF: expected error for malformed move")
+	}
+}
@@ -1,19 +1,101 @@
 package gostockfish
 
-import "math/rand"
+import (
+	"context"
+	"errors"
+	"math/rand"
+)
 
 // MaxMoves is the maximum number of move in the play
 const MaxMoves int = 500
 
+// Phase is the rough stage of a game
+type Phase string
+
+// The phases of a game, see Match.Phase
+const (
+	PhaseOpening    Phase = "opening"
+	PhaseMiddlegame Phase = "middlegame"
+	PhaseEndgame    Phase = "endgame"
+)
+
+// UCIEngine is the interface of a chess engine as used by Match. It is implemented by *Engine
+// and allows to substitute the engine process, i.e. by a mock in tests.
+type UCIEngine interface {
+	NewGame() error
+	SetPosition(moves []string) error
+	SetFENPositionWithMoves(fen string, moves []string) error
+	BestMove() (*BestMove, error)
+	BestMoveContext(ctx context.Context) (*BestMove, error)
+	BestMoveLimits(ctx context.Context, limits SearchLimits) (*BestMove, error)
+	BestMoveLimitsWithProgress(ctx context.Context, limits SearchLimits, onInfo func(*Info)) (*BestMove, error)
+	GoPonder() error
+	PonderHitContext(ctx context.Context) (*BestMove, error)
+	StopPonder() error
+}
+
 // Match represents a match between two engines
 type Match struct {
-	White        string
-	WhiteEngine  *Engine
-	Black        string
-	BlackEngine  *Engine
-	Moves        []string
-	Winner       string
-	WinnerEngine *Engine
+	White         string
+	WhiteEngine   UCIEngine
+	Black         string
+	BlackEngine   UCIEngine
+	StartFEN      string
+	Moves         []string
+	Winner        string
+	WinnerEngine  UCIEngine
+	Ponder        bool // the ponder searches ignore the limits, see MoveContext
+	Limits        *SearchLimits
+	WhiteLimits   *SearchLimits
+	BlackLimits   *SearchLimits
+	Adjudication  *Adjudication
+	Arbitration   *Arbitration
+	OnInfo        func(color Color, info *Info)
+	FiftyMoveRule bool
+	Adjudicated   bool
+	DrawReason    string
+	pondering     map[UCIEngine]string
+	winPlies      int
+	winningWhite  bool
+	drawPlies     int
+	scoreDraw     int
+	lastScore     *int
+	lastBestMove  *BestMove
+	board         *Board
+	boardPlies    int
+	repetitions   map[string]int
+}
+
+// Reasons for a drawn match, see Match.DrawReason
+const (
+	DrawThreefold = "threefold"
+	DrawFiftyMove = "fifty-move"
+)
+
+// Adjudication ends a match early once the engines agree on the outcome, based on the WDL
+// statistics they report with 'UCI_ShowWDL' enabled. A side wins if its win probability
+// exceeds WinThreshold for WinPlies consecutive plies, the game is drawn if the draw
+// probability exceeds DrawThreshold for DrawPlies consecutive plies. Probabilities are in
+// permill; a ply count of 0 disables the rule.
+//
+// Independent of WDL statistics, the game is drawn if the centipawn score of the active engine
+// stays within ScoreDrawWindow of zero (inclusive) for ScoreDrawPlies consecutive plies, i.e.
+// in a dead drawn endgame. Mate scores interrupt the count.
+type Adjudication struct {
+	WinThreshold    int
+	WinPlies        int
+	DrawThreshold   int
+	DrawPlies       int
+	ScoreDrawWindow int
+	ScoreDrawPlies  int
+}
+
+// DefaultAdjudication adjudicates similar to engine testing frameworks
+var DefaultAdjudication = Adjudication{
+	WinThreshold:  990,
+	WinPlies:      8,
+	DrawThreshold: 900,
+	DrawPlies:     10,
 }
 
 // NewMatch setups a chess match between two specified engines. The white player
@@ -23,10 +105,21 @@ type Match struct {
 // shallowEngine := NewEngineWithDepth(10)
 //
 // m := NewMatch("deep", deepEngine, "shallow", shallowEngine)
-func NewMatch(e1 string, engine1 *Engine, e2 string, engine2 *Engine) (*Match, error) {
+func NewMatch(e1 string, engine1 UCIEngine, e2 string, engine2 UCIEngine) (*Match, error) {
+	return newMatch(e1, engine1, e2, engine2, randomIntn(2) == 0)
+}
+
+// NewMatchWithSeed is like NewMatch but chooses the white player with a random source seeded
+// with seed, so the same seed always gives the same colors
+func NewMatchWithSeed(e1 string, engine1 UCIEngine, e2 string, engine2 UCIEngine, seed int64) (*Match, error) {
+	return newMatch(e1, engine1, e2, engine2, rand.New(rand.NewSource(seed)).Intn(2) == 0)
+}
+
+// newMatch setups a chess match, with engine1 as white if e1White is set
+func newMatch(e1 string, engine1 UCIEngine, e2 string, engine2 UCIEngine, e1White bool) (*Match, error) {
 	var m *Match
 
-	if rand.Int()%2 == 0 {
+	if e1White {
 		m = &Match{
 			White:       e1,
 			WhiteEngine: engine1,
@@ -53,62 +146,549 @@ func NewMatch(e1 string, engine1 *Engine, e2 string, engine2 *Engine) (*Match, e
 
 	m.Winner = ""
 	m.WinnerEngine = nil
+	m.FiftyMoveRule = true
+
+	return m, nil
+}
+
+// NewMatchFromMoves setups a chess match between two specified engines that continues
+// after the given moves (i.e. ['e2e4', 'e7e5', ...]) from the start position. Both engines
+// are set to the resulting position. The white player is randomly chosen.
+func NewMatchFromMoves(e1 string, engine1 UCIEngine, e2 string, engine2 UCIEngine, moves []string) (*Match, error) {
+	m, err := NewMatch(e1, engine1, e2, engine2)
+	if err != nil {
+		return nil, err
+	}
+	m.Moves = append([]string{}, moves...)
+
+	err = m.setPosition(engine1)
+	if err != nil {
+		return nil, err
+	}
+	err = m.setPosition(engine2)
+	if err != nil {
+		return nil, err
+	}
 
 	return m, nil
 }
 
+// NewMatchFromFEN setups a chess match between two specified engines starting from the
+// position given in FEN notation. Both engines are set to that position. The white player
+// is randomly chosen.
+func NewMatchFromFEN(e1 string, engine1 UCIEngine, e2 string, engine2 UCIEngine, fen string) (*Match, error) {
+	_, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewMatch(e1, engine1, e2, engine2)
+	if err != nil {
+		return nil, err
+	}
+	m.StartFEN = fen
+
+	err = m.setPosition(engine1)
+	if err != nil {
+		return nil, err
+	}
+	err = m.setPosition(engine2)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// setPosition sets the engine to the current position of the match
+func (match *Match) setPosition(engine positioner) error {
+	return match.setPositionWithMoves(engine, match.Moves)
+}
+
+// positioner is the part of UCIEngine and ArbiterEngine setting the position to search
+type positioner interface {
+	SetPosition(moves []string) error
+	SetFENPositionWithMoves(fen string, moves []string) error
+}
+
+// setPositionWithMoves sets the engine to the start position of the match followed by moves
+func (match *Match) setPositionWithMoves(engine positioner, moves []string) error {
+	if match.StartFEN == "" {
+		return engine.SetPosition(moves)
+	}
+	return engine.SetFENPositionWithMoves(match.StartFEN, moves)
+}
+
 // Move advances the game by single move, if possible. Returns a bool on whether the move was performed.
 func (match *Match) Move() (bool, error) {
-	var activeEngine *Engine
-	var activeEngineName string
-	var inactiveEngine *Engine
-	var inactiveEngineName string
+	return match.MoveContext(context.Background())
+}
+
+// MoveContext is like Move but stops the active engine's search once ctx is done
+//
+// If match.Ponder is set, each engine ponders on the expected reply while its opponent is
+// thinking. When the opponent plays the expected move, the ponder search continues as the
+// engine's search ('ponderhit'), otherwise it is stopped and the engine searches the actual
+// position. Pondering requires two distinct engines. Any ponder search is stopped once the game ends.
+// Pondering ignores match.Limits, match.WhiteLimits and match.BlackLimits: the ponder search is
+// started with Engine.GoPonder, to the engine's depth, and after 'ponderhit' it continues
+// without the limits of the engine's color.
+//
+// If match.Limits is set, the engines search within these limits instead of to their depth,
+// i.e. for a fixed time per move. The limits apply to every move on its own, there is no game
+// clock and no adjudication on time; Engine.MaxSearchTime still caps each search.
+// match.WhiteLimits and match.BlackLimits override match.Limits for the engine of the respective
+// color, i.e. to let an engine limited by 'UCI_LimitStrength' and 'UCI_Elo' think for a fixed
+// time per move while its opponent searches to a fixed depth.
+//
+// If match.OnInfo is set, it is called with the color of the active engine for every info line
+// of its search, i.e. to show the engines thinking in a live broadcast. It is called from the
+// loop reading the engine output and should return quickly. The info lines of a ponder search
+// continued after 'ponderhit' are not reported.
+//
+// The game is drawn once a position occurs for the third time (threefold repetition). If
+// match.FiftyMoveRule is set, as it is by the constructors, the game is drawn once the
+// halfmove clock of the position (see GetFEN) reaches 100, i.e. after fifty moves of each side
+// without a capture or pawn move. Engines do not claim this draw on their own and some keep
+// playing for a win until MaxMoves, so clear it only to study such play. match.DrawReason
+// tells which of the rules ended the game.
+func (match *Match) MoveContext(ctx context.Context) (bool, error) {
+	moved, err := match.move(ctx)
+	if err != nil || !moved {
+		stopErr := match.stopPondering()
+		if err == nil {
+			err = stopErr
+		}
+	}
+	return moved, err
+}
+
+// move plays a single move, see MoveContext
+func (match *Match) move(ctx context.Context) (bool, error) {
+	var activeEngine UCIEngine
+	var bestMove *BestMove
+	var err error
 
 	if len(match.Moves) == MaxMoves {
 		return false, nil
-	} else if len(match.Moves)%2 != 0 {
-		activeEngine = match.BlackEngine
-		activeEngineName = match.Black
-		inactiveEngine = match.WhiteEngine
-		inactiveEngineName = match.White
-	} else {
-		activeEngine = match.WhiteEngine
-		activeEngineName = match.White
-		inactiveEngine = match.BlackEngine
-		inactiveEngineName = match.Black
 	}
-	activeEngine.SetPosition(match.Moves)
-	bestMove, err := activeEngine.BestMove()
+	if match.Ponder && match.WhiteEngine == match.BlackEngine {
+		return false, errors.New("Pondering requires two distinct engines")
+	}
+	whiteToMove, err := match.whiteToMove()
 	if err != nil {
 		return false, err
 	}
+	if whiteToMove {
+		activeEngine = match.WhiteEngine
+	} else {
+		activeEngine = match.BlackEngine
+	}
+
+	if expected, pondering := match.pondering[activeEngine]; pondering {
+		delete(match.pondering, activeEngine)
+		if len(match.Moves) > 0 && match.Moves[len(match.Moves)-1] == expected {
+			bestMove, err = activeEngine.PonderHitContext(ctx)
+		} else {
+			err = activeEngine.StopPonder()
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	if bestMove == nil {
+		if err := match.setPosition(activeEngine); err != nil {
+			return false, err
+		}
+		limits := match.limits(whiteToMove)
+		if match.OnInfo != nil {
+			color := ColorBlack
+			if whiteToMove {
+				color = ColorWhite
+			}
+			bestMove, err = activeEngine.BestMoveLimitsWithProgress(ctx, limits, func(info *Info) {
+				match.OnInfo(color, info)
+			})
+		} else if limits != (SearchLimits{}) {
+			bestMove, err = activeEngine.BestMoveLimits(ctx, limits)
+		} else {
+			bestMove, err = activeEngine.BestMoveContext(ctx)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if bestMove.Move == "(none)" {
+		// no legal move left: checkmate if the engine reports mate 0, stalemate otherwise
+		if bestMove.Checkmated() {
+			match.setWinner(!whiteToMove)
+		}
+		return false, nil
+	}
 	match.Moves = append(match.Moves, bestMove.Move)
+	match.lastBestMove = bestMove
 
-	if bestMove.Info.Score.Eval == "mate" {
-		matenum := bestMove.Info.Score.Value
-		if matenum > 0 {
-			match.WinnerEngine = activeEngine
-			match.Winner = activeEngineName
-		} else if matenum < 0 {
-			match.WinnerEngine = inactiveEngine
-			match.Winner = inactiveEngineName
+	if bestMove.Info != nil && bestMove.Info.Score.Eval == "mate" {
+		// the score is given from the point of view of the side to move
+		if bestMove.Info.Score.Value > 0 {
+			match.setWinner(whiteToMove)
+		} else {
+			match.setWinner(!whiteToMove)
 		}
 		return false, nil
 	}
 
+	board, err := match.track()
+	if err != nil {
+		return false, err
+	}
+	if match.repetitions[board.Key()] >= 3 {
+		match.DrawReason = DrawThreefold
+		return false, nil
+	}
+	if match.FiftyMoveRule && board.HalfmoveClock >= 100 {
+		match.DrawReason = DrawFiftyMove
+		return false, nil
+	}
+
+	if match.adjudicate(bestMove, whiteToMove) {
+		return false, nil
+	}
+	decided, err := match.arbitrate(bestMove, whiteToMove)
+	if err != nil || decided {
+		return false, err
+	}
+
 	if bestMove.Ponder != "(none)" {
+		if match.Ponder && bestMove.Ponder != "" {
+			err = match.startPondering(activeEngine, bestMove.Ponder)
+			if err != nil {
+				return false, err
+			}
+		}
 		return true, nil
 	}
 
 	return false, nil
 }
 
+// limits returns the search limits of the engine of the given color, the zero SearchLimits
+// (search to the engine's depth) if there are none
+func (match *Match) limits(white bool) SearchLimits {
+	limits := match.Limits
+	if white && match.WhiteLimits != nil {
+		limits = match.WhiteLimits
+	} else if !white && match.BlackLimits != nil {
+		limits = match.BlackLimits
+	}
+	if limits == nil {
+		return SearchLimits{}
+	}
+	return *limits
+}
+
+// adjudicate counts the plies for which the WDL statistics of the search meet the thresholds of
+// match.Adjudication and sets the result once the game is decided
+func (match *Match) adjudicate(bestMove *BestMove, whiteToMove bool) bool {
+	rules := match.Adjudication
+	if rules == nil || bestMove.Info == nil {
+		return false
+	}
+	wdl := bestMove.Info.WDL
+
+	if wdl.Draw > rules.DrawThreshold {
+		match.drawPlies++
+	} else {
+		match.drawPlies = 0
+	}
+
+	score := bestMove.Info.Score
+	if score.Eval == "cp" && abs(score.Value) <= rules.ScoreDrawWindow {
+		match.scoreDraw++
+	} else {
+		match.scoreDraw = 0
+	}
+
+	winning, white := false, false
+	if wdl.Win > rules.WinThreshold {
+		winning, white = true, whiteToMove
+	} else if wdl.Loss > rules.WinThreshold {
+		winning, white = true, !whiteToMove
+	}
+	if winning && match.winPlies > 0 && white == match.winningWhite {
+		match.winPlies++
+	} else if winning {
+		match.winPlies = 1
+		match.winningWhite = white
+	} else {
+		match.winPlies = 0
+	}
+
+	if rules.WinPlies > 0 && match.winPlies >= rules.WinPlies {
+		match.setWinner(match.winningWhite)
+		match.Adjudicated = true
+		return true
+	}
+	if rules.DrawPlies > 0 && match.drawPlies >= rules.DrawPlies {
+		match.Adjudicated = true
+		return true
+	}
+	if rules.ScoreDrawPlies > 0 && match.scoreDraw >= rules.ScoreDrawPlies {
+		match.Adjudicated = true
+		return true
+	}
+	return false
+}
+
+// startPondering lets the engine ponder on the expected reply of its opponent
+func (match *Match) startPondering(engine UCIEngine, expected string) error {
+	moves := append(append([]string{}, match.Moves...), expected)
+	err := match.setPositionWithMoves(engine, moves)
+	if err != nil {
+		return err
+	}
+	err = engine.GoPonder()
+	if err != nil {
+		return err
+	}
+	if match.pondering == nil {
+		match.pondering = map[UCIEngine]string{}
+	}
+	match.pondering[engine] = expected
+	return nil
+}
+
+// stopPondering stops the ponder searches of both engines, if any
+func (match *Match) stopPondering() error {
+	var err error
+	for engine := range match.pondering {
+		stopErr := engine.StopPonder()
+		if err == nil {
+			err = stopErr
+		}
+		delete(match.pondering, engine)
+	}
+	return err
+}
+
+// whiteToMove reports whether white is to move in the current position. Returns an error if
+// match.StartFEN cannot be parsed.
+func (match *Match) whiteToMove() (bool, error) {
+	board, err := match.startBoard()
+	if err != nil {
+		return false, err
+	}
+	return board.WhiteToMove == (len(match.Moves)%2 == 0), nil
+}
+
+// Board returns the current position of the match
+func (match *Match) Board() (*Board, error) {
+	board, err := match.startBoard()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range match.Moves {
+		err = board.Apply(m)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return board, nil
+}
+
+// track brings the board of the current position and the number of occurrences of each
+// position (by Board.Key) up to date with the moves of the match
+func (match *Match) track() (*Board, error) {
+	if match.board == nil || match.boardPlies > len(match.Moves) {
+		board, err := match.startBoard()
+		if err != nil {
+			return nil, err
+		}
+		match.board, match.boardPlies = board, 0
+		match.repetitions = map[string]int{board.Key(): 1}
+	}
+	for ; match.boardPlies < len(match.Moves); match.boardPlies++ {
+		err := match.board.Apply(match.Moves[match.boardPlies])
+		if err != nil {
+			return nil, err
+		}
+		match.repetitions[match.board.Key()]++
+	}
+	return match.board, nil
+}
+
+// GetFEN returns the current position of the match in FEN notation
+func (match *Match) GetFEN() (string, error) {
+	board, err := match.Board()
+	if err != nil {
+		return "", err
+	}
+	return board.FEN(), nil
+}
+
+// MovesSAN returns the moves of the match in standard algebraic notation (i.e. 'Nf3')
+func (match *Match) MovesSAN() ([]string, error) {
+	board, err := match.startBoard()
+	if err != nil {
+		return nil, err
+	}
+	var moves []string
+	for _, m := range match.Moves {
+		san, err := board.UCIToSAN(m)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, san)
+		board.Apply(m)
+	}
+	return moves, nil
+}
+
+// startBoard returns the start position of the match
+func (match *Match) startBoard() (*Board, error) {
+	fen := match.StartFEN
+	if fen == "" {
+		fen = StartFEN
+	}
+	return ParseFEN(fen)
+}
+
+// SideToMove returns the color of the side to move, assuming white started if match.StartFEN
+// cannot be parsed, like FullMoveNumber
+func (match *Match) SideToMove() Color {
+	whiteToMove, err := match.whiteToMove()
+	if err != nil {
+		whiteToMove = len(match.Moves)%2 == 0
+	}
+	if whiteToMove {
+		return ColorWhite
+	}
+	return ColorBlack
+}
+
+// FullMoveNumber returns the number of the current move, starting at 1 and incremented after black's move
+func (match *Match) FullMoveNumber() int {
+	number := 1
+	whiteStarts := true
+	if match.StartFEN != "" {
+		board, err := ParseFEN(match.StartFEN)
+		if err == nil {
+			number = board.FullmoveNumber
+			whiteStarts = board.WhiteToMove
+		}
+	}
+	plies := len(match.Moves)
+	if !whiteStarts {
+		plies++
+	}
+	return number + plies/2
+}
+
+// Phase estimates the stage of the game from the material left on the board, counting 3 for
+// knights and bishops, 5 for rooks and 9 for queens (62 in the start position):
+// - endgame with 26 or less, i.e. at most two rooks and a minor piece per side
+// - opening in the first 10 moves with at most one minor piece per side exchanged
+// - middlegame otherwise
+func (match *Match) Phase() (Phase, error) {
+	board, err := match.Board()
+	if err != nil {
+		return "", err
+	}
+	material := 0
+	for _, piece := range board.Squares {
+		switch toUpper(piece) {
+		case 'N', 'B':
+			material += 3
+		case 'R':
+			material += 5
+		case 'Q':
+			material += 9
+		}
+	}
+	if material <= 26 {
+		return PhaseEndgame, nil
+	}
+	if board.FullmoveNumber <= 10 && material >= 56 {
+		return PhaseOpening, nil
+	}
+	return PhaseMiddlegame, nil
+}
+
+// setWinner declares white or black the winner of the match
+func (match *Match) setWinner(white bool) {
+	if white {
+		match.Winner = match.White
+		match.WinnerEngine = match.WhiteEngine
+	} else {
+		match.Winner = match.Black
+		match.WinnerEngine = match.BlackEngine
+	}
+}
+
 // Run plays the game until completion or 200 moves have been played,
 // returning the winning engine name. Returns empty string if there
 // is a draw.
 func (match *Match) Run() (string, error) {
+	return match.RunContext(context.Background())
+}
+
+// Ply is a move of a match, see EachPly
+type Ply struct {
+	// FEN is the position before the move
+	FEN      string
+	Color    Color
+	Move     string
+	BestMove *BestMove
+}
+
+// EachPly plays the match like RunContext and calls yield with each move as soon as it is
+// played, i.e. to produce training positions without keeping whole games. The match ends when
+// the game ends (checkmate, stalemate, adjudication, ...) or when yield returns false. Returns
+// the winner, empty for a draw or an unfinished game.
+func (match *Match) EachPly(ctx context.Context, yield func(ply *Ply) bool) (string, error) {
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return "", err
+		}
+		fen, err := match.GetFEN()
+		if err != nil {
+			return "", err
+		}
+		color := match.SideToMove()
+		plies := len(match.Moves)
+
+		moved, err := match.MoveContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(match.Moves) > plies {
+			ply := &Ply{
+				FEN:      fen,
+				Color:    color,
+				Move:     match.Moves[plies],
+				BestMove: match.lastBestMove,
+			}
+			if !yield(ply) {
+				return match.Winner, match.stopPondering()
+			}
+		}
+		if !moved {
+			return match.Winner, nil
+		}
+	}
+}
+
+// RunContext is like Run but stops once ctx is done, returning ctx.Err(). The moves played
+// until then remain available in match.Moves.
+func (match *Match) RunContext(ctx context.Context) (string, error) {
 	for {
-		move, err := match.Move()
+		err := ctx.Err()
+		if err != nil {
+			return "", err
+		}
+		move, err := match.MoveContext(ctx)
 		if err != nil {
 			return "", err
 		}
@@ -1,6 +1,31 @@
 package gostockfish
 
-import "testing"
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPlyEngine returns a fake engine that answers the search of the n-th ply (counted from
+// the moves in the last position command) with replies[n]
+func newPlyEngine(replies map[int][]string) *Engine {
+	ply := 0
+	engine, _ := newFakeEngine(func(command string) []string {
+		if strings.HasPrefix(command, "position ") {
+			ply = 0
+			if i := strings.Index(command, " moves"); i >= 0 {
+				ply = len(strings.Fields(command[i+len(" moves"):]))
+			}
+		}
+		if strings.HasPrefix(command, "go ") {
+			return replies[ply]
+		}
+		return nil
+	})
+	return engine
+}
 
 func TestQuickCheckmate(t *testing.T) {
 	// 1. e4 e5 2. Bc4 Nc6 3. Qf3 d6
@@ -13,19 +38,735 @@ func TestQuickCheckmate(t *testing.T) {
 		t.Fatalf(err.Error())
 	}
 
-	// match creation must be before calling SetPosition() as NewMatch() resets position
-	m, err := NewMatch("e1", e1, "e2", e2)
+	m, err := NewMatchFromMoves("e1", e1, "e2", e2, []string{"e2e4", "e7e5", "f1c4", "b8c6", "d1f3", "d7d6"})
 
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
 
-	e1.SetPosition([]string{"e2e4", "e7e5", "f1c4", "b8c6", "d1f3", "d7d6"})
-	e2.SetPosition([]string{"e2e4", "e7e5", "f1c4", "b8c6", "d1f3", "d7d6"})
-
 	m.Run()
 
 	if m.Winner != "e1" && m.Winner != "e2" {
 		t.Fatalf("Expected winner \"e1\" or \"e2\", got \"%s\"", m.Winner)
 	}
 }
+
+func TestRunContext(t *testing.T) {
+	searches := 0
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command == "stop" {
+			return []string{"bestmove a2a3"}
+		}
+		if command != "go depth 2" {
+			return nil
+		}
+		searches++
+		if searches > 2 {
+			// never finish the third search unless stopped
+			return []string{"info depth 1 seldepth 1 multipv 1 score cp 0 nodes 20 nps 20000 tbhits 0 time 1 pv a2a3"}
+		}
+		if searches == 2 {
+			return []string{
+				"info depth 2 seldepth 2 multipv 1 score cp -10 nodes 40 nps 40000 tbhits 0 time 1 pv e7e5 g1f3",
+				"bestmove e7e5 ponder g1f3",
+			}
+		}
+		return []string{
+			"info depth 2 seldepth 2 multipv 1 score cp 10 nodes 40 nps 40000 tbhits 0 time 1 pv e2e4 e7e5",
+			"bestmove e2e4 ponder e7e5",
+		}
+	})
+
+	m, err := NewMatch("e1", engine, "e2", engine)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = m.RunContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("RunContext: expected %v, actual %v", context.DeadlineExceeded, err)
+	}
+	if len(m.Moves) != 2 {
+		t.Errorf("RunContext: expected 2 moves before cancellation, actual %v", m.Moves)
+	}
+
+	err = engine.IsReady()
+	if err != nil {
+		t.Errorf("IsReady() after cancellation: %s", err)
+	}
+}
+
+func TestMateScores(t *testing.T) {
+	var tests = []struct {
+		name    string
+		replies map[int][]string
+		winner  string
+		moves   int
+	}{
+		{
+			"white mates",
+			map[int][]string{
+				0: {"info depth 5 seldepth 5 multipv 1 score mate 1 nodes 50 nps 5000 tbhits 0 time 10 pv e2e4", "bestmove e2e4"},
+			},
+			"white",
+			1,
+		},
+		{
+			"black mates",
+			map[int][]string{
+				0: {"info depth 5 seldepth 5 multipv 1 score cp 50 nodes 50 nps 5000 tbhits 0 time 10 pv f2f3", "bestmove f2f3"},
+				1: {"info depth 5 seldepth 5 multipv 1 score mate 2 nodes 50 nps 5000 tbhits 0 time 10 pv e7e5", "bestmove e7e5"},
+			},
+			"black",
+			2,
+		},
+		{
+			"white is getting mated",
+			map[int][]string{
+				0: {"info depth 5 seldepth 5 multipv 1 score mate -3 nodes 50 nps 5000 tbhits 0 time 10 pv f2f3", "bestmove f2f3"},
+			},
+			"black",
+			1,
+		},
+		{
+			"black is checkmated",
+			map[int][]string{
+				0: {"info depth 5 seldepth 5 multipv 1 score cp 50 nodes 50 nps 5000 tbhits 0 time 10 pv e2e4", "bestmove e2e4 ponder e7e5"},
+				1: {"info depth 0 score mate 0", "bestmove (none)"},
+			},
+			"white",
+			1,
+		},
+		{
+			"stalemate",
+			map[int][]string{
+				0: {"info depth 0 score cp 0", "bestmove (none)"},
+			},
+			"",
+			0,
+		},
+	}
+	for _, tt := range tests {
+		engine := newPlyEngine(tt.replies)
+		m, err := NewMatch("e1", engine, "e2", engine)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		m.White, m.Black = "white", "black"
+
+		winner, err := m.Run()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if winner != tt.winner {
+			t.Errorf("%s: expected winner \"%s\", actual \"%s\"", tt.name, tt.winner, winner)
+		}
+		if len(m.Moves) != tt.moves {
+			t.Errorf("%s: expected %d moves, actual %v", tt.name, tt.moves, m.Moves)
+		}
+	}
+}
+
+func TestAdjudication(t *testing.T) {
+	info := func(wdl string, move string) []string {
+		return []string{
+			"info depth 10 seldepth 12 multipv 1 score cp 0 wdl " + wdl + " nodes 500 nps 50000 tbhits 0 time 10 pv " + move,
+			"bestmove " + move,
+		}
+	}
+	var tests = []struct {
+		name    string
+		replies map[int][]string
+		winner  string
+		moves   int
+	}{
+		{
+			"draw",
+			map[int][]string{
+				0: info("20 960 20", "e2e4"),
+				1: info("20 960 20", "e7e5"),
+				2: info("20 960 20", "g1f3"),
+			},
+			"",
+			3,
+		},
+		{
+			"draw interrupted",
+			map[int][]string{
+				0: info("20 960 20", "e2e4"),
+				1: info("200 790 10", "e7e5"),
+				2: info("20 960 20", "g1f3"),
+				3: info("20 960 20", "b8c6"),
+				4: info("20 960 20", "f1c4"),
+			},
+			"",
+			5,
+		},
+		{
+			"white wins",
+			map[int][]string{
+				0: info("995 5 0", "e2e4"),
+				1: info("0 4 996", "e7e5"),
+				2: info("999 1 0", "g1f3"),
+			},
+			"white",
+			3,
+		},
+		{
+			"winner changes",
+			map[int][]string{
+				0: info("995 5 0", "e2e4"),
+				1: info("995 5 0", "e7e5"),
+				2: info("0 5 995", "g1f3"),
+				3: info("996 4 0", "b8c6"),
+			},
+			"black",
+			4,
+		},
+	}
+	for _, tt := range tests {
+		engine := newPlyEngine(tt.replies)
+		m, err := NewMatch("e1", engine, "e2", engine)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		m.White, m.Black = "white", "black"
+		m.Adjudication = &Adjudication{WinThreshold: 990, WinPlies: 3, DrawThreshold: 900, DrawPlies: 3}
+
+		winner, err := m.Run()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if winner != tt.winner || !m.Adjudicated {
+			t.Errorf("%s: expected adjudicated winner \"%s\", actual \"%s\" (adjudicated %v)", tt.name, tt.winner, winner, m.Adjudicated)
+		}
+		if len(m.Moves) != tt.moves {
+			t.Errorf("%s: expected %d moves, actual %v", tt.name, tt.moves, m.Moves)
+		}
+	}
+}
+
+func TestScoreDrawAdjudication(t *testing.T) {
+	info := func(score string, move string) []string {
+		return []string{
+			"info depth 18 seldepth 20 multipv 1 score " + score + " nodes 5000 nps 500000 tbhits 0 time 10 pv " + move,
+			"bestmove " + move,
+		}
+	}
+	// rook endgame with the kings in front of the pawns: 1. Rb1 Rb8 2. Ra1 Ra8 3. Rb1 ...
+	replies := map[int][]string{
+		0: info("cp 4", "a1b1"),
+		1: info("cp -3", "a8b8"),
+		2: info("cp 25", "b1a1"),
+		3: info("cp 2", "b8a8"),
+		4: info("cp 0", "a1b1"),
+		5: info("cp -5", "a8b8"),
+		6: info("cp 1", "b1a1"),
+		7: info("cp 0", "b8a8"),
+	}
+	engine := newPlyEngine(replies)
+	m, err := NewMatchFromFEN("e1", engine, "e2", engine, "r5k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 40")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.Adjudication = &Adjudication{ScoreDrawWindow: 5, ScoreDrawPlies: 4}
+
+	winner, err := m.Run()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if winner != "" || !m.Adjudicated {
+		t.Errorf("ScoreDrawAdjudication: expected adjudicated draw, actual winner \"%s\" (adjudicated %v)", winner, m.Adjudicated)
+	}
+	if len(m.Moves) != 7 {
+		t.Errorf("ScoreDrawAdjudication: expected 7 moves, actual %v", m.Moves)
+	}
+}
+
+func TestNewMatchWithSeed(t *testing.T) {
+	e1, e2 := &mockEngine{}, &mockEngine{}
+	whites := map[string]bool{}
+	for seed := int64(1); seed <= 8; seed++ {
+		m1, err := NewMatchWithSeed("e1", e1, "e2", e2, seed)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		m2, err := NewMatchWithSeed("e1", e1, "e2", e2, seed)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if m1.White != m2.White || m1.WhiteEngine != m2.WhiteEngine {
+			t.Errorf("NewMatchWithSeed(%d): expected the same white player, actual %s and %s", seed, m1.White, m2.White)
+		}
+		whites[m1.White] = true
+	}
+	if !whites["e1"] || !whites["e2"] {
+		t.Errorf("NewMatchWithSeed: expected both engines to play white for some seed, actual %v", whites)
+	}
+}
+
+func TestFiftyMoveRule(t *testing.T) {
+	shuffle := map[int][]string{
+		0: {"bestmove a1b1"},
+		1: {"bestmove a8b8"},
+		2: {"bestmove b1a1"},
+		3: {"bestmove b8a8"},
+	}
+	for _, rule := range []bool{false, true} {
+		engine := newPlyEngine(shuffle)
+		m, err := NewMatchFromFEN("e1", engine, "e2", engine, "r5k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 97 80")
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		m.FiftyMoveRule = rule
+
+		var moved bool
+		for i := 0; i < 3; i++ {
+			moved, err = m.Move()
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+		}
+		// the third move reaches a halfmove clock of 100
+		if moved == rule {
+			t.Errorf("Move() with FiftyMoveRule %v: expected the game to continue %v, actual %v", rule, !rule, moved)
+		}
+		fen, err := m.GetFEN()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if expected := "1r4k1/5ppp/8/8/8/8/5PPP/R5K1 b - - 100 81"; fen != expected {
+			t.Errorf("GetFEN(): expected %s, actual %s", expected, fen)
+		}
+	}
+}
+
+func TestEachPly(t *testing.T) {
+	moves := []string{"f2f3", "e7e5", "g2g4", "d8h4"}
+	engine := &mockEngine{moves: moves}
+	m, err := NewMatch("e1", engine, "e2", engine)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.White, m.Black = "white", "black"
+
+	var plies []*Ply
+	winner, err := m.EachPly(context.Background(), func(ply *Ply) bool {
+		plies = append(plies, ply)
+		return true
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if winner != "black" {
+		t.Errorf("EachPly: expected black to win, actual \"%s\"", winner)
+	}
+	if len(plies) != len(moves) {
+		t.Fatalf("EachPly: expected %d plies, actual %d", len(moves), len(plies))
+	}
+	for i, ply := range plies {
+		if ply.Move != moves[i] || ply.BestMove == nil || ply.BestMove.Move != moves[i] {
+			t.Errorf("EachPly: expected ply %d to be %s, actual %+v", i, moves[i], ply)
+		}
+	}
+	if plies[0].FEN != StartFEN || plies[0].Color != ColorWhite {
+		t.Errorf("EachPly: expected the first ply from the start position by white, actual %s by %s", plies[0].FEN, plies[0].Color)
+	}
+	if expected := "rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2"; plies[3].FEN != expected || plies[3].Color != ColorBlack {
+		t.Errorf("EachPly: expected the last ply from %s by black, actual %s by %s", expected, plies[3].FEN, plies[3].Color)
+	}
+
+	m, err = NewMatch("e1", engine, "e2", engine)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	count := 0
+	winner, err = m.EachPly(context.Background(), func(ply *Ply) bool {
+		count++
+		return count < 2
+	})
+	if err != nil || winner != "" || count != 2 || len(m.Moves) != 2 {
+		t.Errorf("EachPly stopped after 2 plies: unexpected winner \"%s\", %d plies, moves %v (%v)", winner, count, m.Moves, err)
+	}
+}
+
+func TestNewMatchFromFEN(t *testing.T) {
+	var positions []string
+	engine, _ := newFakeEngine(func(command string) []string {
+		if strings.HasPrefix(command, "position ") {
+			positions = append(positions, command)
+		}
+		if strings.HasPrefix(command, "go ") {
+			return []string{"info depth 5 seldepth 5 multipv 1 score mate 1 nodes 50 nps 5000 tbhits 0 time 10 pv h7h8q", "bestmove h7h8q"}
+		}
+		return nil
+	})
+
+	m, err := NewMatchFromFEN("e1", engine, "e2", engine, "4k3/7P/8/8/8/8/8/4K3 b - - 0 1")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.White, m.Black = "white", "black"
+
+	winner, err := m.Run()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if winner != "black" {
+		t.Errorf("NewMatchFromFEN: expected black (to move) to win, actual \"%s\"", winner)
+	}
+	expected := "position fen 4k3/7P/8/8/8/8/8/4K3 b - - 0 1 moves "
+	for _, position := range positions {
+		if position != expected {
+			t.Errorf("NewMatchFromFEN: expected \"%s\", actual \"%s\"", expected, position)
+		}
+	}
+
+	_, err = NewMatchFromFEN("e1", engine, "e2", engine, "not a fen")
+	if err == nil {
+		t.Errorf("NewMatchFromFEN: expected error for invalid FEN")
+	}
+}
+
+func TestMatchPosition(t *testing.T) {
+	m := &Match{Moves: []string{"e2e4", "e7e5", "g1f3"}}
+	if m.SideToMove() != ColorBlack || m.FullMoveNumber() != 2 {
+		t.Errorf("Match after 3 plies: expected black to move in move 2, actual %s in move %d", m.SideToMove(), m.FullMoveNumber())
+	}
+	phase, err := m.Phase()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if phase != PhaseOpening {
+		t.Errorf("Phase(): expected %s, actual %s", PhaseOpening, phase)
+	}
+
+	m = &Match{StartFEN: "4k3/8/8/r7/8/8/3P4/4K2R b K - 3 40", Moves: []string{"a5a4"}}
+	if m.SideToMove() != ColorWhite || m.FullMoveNumber() != 41 {
+		t.Errorf("Match from FEN: expected white to move in move 41, actual %s in move %d", m.SideToMove(), m.FullMoveNumber())
+	}
+	phase, err = m.Phase()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if phase != PhaseEndgame {
+		t.Errorf("Phase(): expected %s, actual %s", PhaseEndgame, phase)
+	}
+
+	engine, _ := newFakeEngine(nil)
+	m = &Match{StartFEN: "4k3", WhiteEngine: engine, BlackEngine: engine, Moves: []string{"e8d8"}}
+	if m.SideToMove() != ColorBlack {
+		t.Errorf("Match from invalid FEN: expected black to move, actual %s", m.SideToMove())
+	}
+	if _, err := m.Move(); err == nil {
+		t.Errorf("Move() from invalid FEN: expected error")
+	}
+}
+
+func TestMatchLimits(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		switch command {
+		case "go depth 12 movetime 250":
+			return []string{"info depth 0 score mate 0", "bestmove (none)"}
+		case "go movetime 100":
+			return []string{"bestmove e2e4"}
+		}
+		return nil
+	})
+	m, err := NewMatch("e1", engine, "e2", engine)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.Limits = &SearchLimits{MoveTime: 100 * time.Millisecond}
+	moved, err := m.Move()
+	if err != nil || !moved {
+		t.Fatalf("Move(): expected a move, actual %v (%v)", moved, err)
+	}
+	m.Limits = &SearchLimits{Depth: 12, MoveTime: 250 * time.Millisecond}
+	moved, err = m.Move()
+	if err != nil || moved {
+		t.Fatalf("Move(): expected the game to end, actual %v (%v)", moved, err)
+	}
+
+	var searches []string
+	for _, command := range fake.sent() {
+		if strings.HasPrefix(command, "go") {
+			searches = append(searches, command)
+		}
+	}
+	expected := []string{"go movetime 100", "go depth 12 movetime 250"}
+	if !reflect.DeepEqual(searches, expected) {
+		t.Errorf("Move() with limits: expected %v, actual %v", expected, searches)
+	}
+}
+
+func TestMatchColorLimits(t *testing.T) {
+	respond := func(move string) func(command string) []string {
+		return func(command string) []string {
+			if strings.HasPrefix(command, "go ") {
+				return []string{"bestmove " + move}
+			}
+			return nil
+		}
+	}
+	full, fullFake := newFakeEngine(respond("e2e4"))
+	full.Depth = 20
+	limited, limitedFake := newFakeEngine(respond("e7e5"))
+	m, err := NewMatch("full", full, "limited", limited)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	m.White, m.WhiteEngine, m.Black, m.BlackEngine = "full", full, "limited", limited
+	m.BlackLimits = &SearchLimits{MoveTime: 500 * time.Millisecond}
+
+	for i := 0; i < 2; i++ {
+		moved, err := m.Move()
+		if err != nil || !moved {
+			t.Fatalf("Move(): expected a move, actual %v (%v)", moved, err)
+		}
+	}
+
+	search := func(commands []string) string {
+		for _, command := range commands {
+			if strings.HasPrefix(command, "go ") {
+				return command
+			}
+		}
+		return ""
+	}
+	if actual := search(fullFake.sent()); actual != "go depth 20" {
+		t.Errorf("Move() with black limits: expected white to search \"go depth 20\", actual \"%s\"", actual)
+	}
+	if actual := search(limitedFake.sent()); actual != "go movetime 500" {
+		t.Errorf("Move() with black limits: expected black to search \"go movetime 500\", actual \"%s\"", actual)
+	}
+}
+
+func TestMatchOnInfo(t *testing.T) {
+	engine := newPlyEngine(map[int][]string{
+		0: {
+			"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+			"info depth 2 seldepth 2 multipv 1 score cp 30 nodes 400 nps 40000 tbhits 0 time 10 pv e2e4 e7e5",
+			"bestmove e2e4 ponder e7e5",
+		},
+		1: {
+			"info depth 1 seldepth 1 multipv 1 score cp -25 nodes 20 nps 20000 tbhits 0 time 1 pv e7e5",
+			"bestmove e7e5",
+		},
+	})
+	m, err := NewMatch("e1", engine, "e2", engine)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	var colors []Color
+	var depths []int
+	m.OnInfo = func(color Color, info *Info) {
+		colors = append(colors, color)
+		depths = append(depths, info.Depth)
+	}
+	for i := 0; i < 2; i++ {
+		moved, err := m.Move()
+		if err != nil || !moved {
+			t.Fatalf("Move(): expected a move, actual %v (%v)", moved, err)
+		}
+	}
+
+	expectedColors := []Color{ColorWhite, ColorWhite, ColorBlack}
+	if !reflect.DeepEqual(colors, expectedColors) {
+		t.Errorf("OnInfo: expected colors %v, actual %v", expectedColors, colors)
+	}
+	if expected := []int{1, 2, 1}; !reflect.DeepEqual(depths, expected) {
+		t.Errorf("OnInfo: expected depths %v, actual %v", expected, depths)
+	}
+}
+
+func TestMovesSAN(t *testing.T) {
+	m := &Match{Moves: []string{"e2e4", "e7e5", "g1f3", "b8c6", "f1b5"}}
+	moves, err := m.MovesSAN()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := []string{"e4", "e5", "Nf3", "Nc6", "Bb5"}
+	if !reflect.DeepEqual(moves, expected) {
+		t.Errorf("MovesSAN(): expected %v, actual %v", expected, moves)
+	}
+
+	m = &Match{StartFEN: "4k3/8/8/r7/8/8/3P4/4K2R b K - 3 40", Moves: []string{"a5a1", "e1e2"}}
+	moves, err = m.MovesSAN()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected = []string{"Ra1+", "Ke2"}
+	if !reflect.DeepEqual(moves, expected) {
+		t.Errorf("MovesSAN() from FEN: expected %v, actual %v", expected, moves)
+	}
+}
+
+func TestPonderMatch(t *testing.T) {
+	white, whiteFake := newFakeEngine(func(command string) []string {
+		switch command {
+		case "go depth 2":
+			return []string{"info depth 2 seldepth 2 multipv 1 score cp 30 nodes 400 nps 40000 tbhits 0 time 10 pv e2e4 e7e5", "bestmove e2e4 ponder e7e5"}
+		case "ponderhit":
+			return []string{"info depth 2 seldepth 2 multipv 1 score mate 3 nodes 400 nps 40000 tbhits 0 time 10 pv g1f3", "bestmove g1f3 ponder b8c6"}
+		case "stop":
+			return []string{"bestmove a2a3"}
+		}
+		return nil
+	})
+	black, blackFake := newFakeEngine(func(command string) []string {
+		switch command {
+		case "go depth 2":
+			return []string{"info depth 2 seldepth 2 multipv 1 score cp -30 nodes 400 nps 40000 tbhits 0 time 10 pv e7e5 g1f3", "bestmove e7e5 ponder g1f3"}
+		case "stop":
+			return []string{"bestmove b8c6"}
+		}
+		return nil
+	})
+
+	m := &Match{
+		White:       "white",
+		WhiteEngine: white,
+		Black:       "black",
+		BlackEngine: black,
+		Ponder:      true,
+	}
+	winner, err := m.Run()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if winner != "white" {
+		t.Errorf("PonderMatch: expected white to win, actual \"%s\"", winner)
+	}
+	if expected := []string{"e2e4", "e7e5", "g1f3"}; !reflect.DeepEqual(m.Moves, expected) {
+		t.Errorf("PonderMatch: expected moves %v, actual %v", expected, m.Moves)
+	}
+
+	expectedWhite := []string{
+		"position startpos moves ", "isready", "go depth 2",
+		"position startpos moves e2e4 e7e5", "isready", "go ponder depth 2", "isready",
+		"ponderhit",
+	}
+	if actual := whiteFake.sent(); !reflect.DeepEqual(actual, expectedWhite) {
+		t.Errorf("PonderMatch: expected white to receive %v, actual %v", expectedWhite, actual)
+	}
+	expectedBlack := []string{
+		"position startpos moves e2e4", "isready", "go depth 2",
+		"position startpos moves e2e4 e7e5 g1f3", "isready", "go ponder depth 2", "isready",
+		"stop",
+	}
+	if actual := blackFake.sent(); !reflect.DeepEqual(actual, expectedBlack) {
+		t.Errorf("PonderMatch: expected black to receive %v, actual %v", expectedBlack, actual)
+	}
+}
+
+// mockEngine is a UCIEngine which plays a fixed sequence of moves without an engine process
+type mockEngine struct {
+	moves    []string
+	position []string
+}
+
+func (engine *mockEngine) NewGame() error {
+	engine.position = nil
+	return nil
+}
+
+func (engine *mockEngine) SetPosition(moves []string) error {
+	engine.position = moves
+	return nil
+}
+
+func (engine *mockEngine) SetFENPositionWithMoves(fen string, moves []string) error {
+	engine.position = moves
+	return nil
+}
+
+func (engine *mockEngine) BestMove() (*BestMove, error) {
+	return engine.BestMoveContext(context.Background())
+}
+
+func (engine *mockEngine) BestMoveContext(ctx context.Context) (*BestMove, error) {
+	if len(engine.position) >= len(engine.moves) {
+		return &BestMove{Move: "(none)", Info: &Info{Score: Score{Eval: "mate", Value: 0}}}, nil
+	}
+	return &BestMove{Move: engine.moves[len(engine.position)], Info: &Info{}}, nil
+}
+
+func (engine *mockEngine) BestMoveLimits(ctx context.Context, limits SearchLimits) (*BestMove, error) {
+	return engine.BestMoveContext(ctx)
+}
+
+func (engine *mockEngine) BestMoveLimitsWithProgress(ctx context.Context, limits SearchLimits, onInfo func(*Info)) (*BestMove, error) {
+	bestMove, err := engine.BestMoveContext(ctx)
+	if err == nil {
+		onInfo(bestMove.Info)
+	}
+	return bestMove, err
+}
+
+func (engine *mockEngine) GoPonder() error {
+	return nil
+}
+
+func (engine *mockEngine) PonderHitContext(ctx context.Context) (*BestMove, error) {
+	return engine.BestMoveContext(ctx)
+}
+
+func (engine *mockEngine) StopPonder() error {
+	return nil
+}
+
+func TestMockEngineMatch(t *testing.T) {
+	// fool's mate
+	engine := &mockEngine{moves: []string{"f2f3", "e7e5", "g2g4", "d8h4"}}
+	m, err := NewMatch("e1", engine, "e2", engine)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	m.Run()
+
+	if !reflect.DeepEqual(m.Moves, engine.moves) {
+		t.Errorf("Run: expected moves %v, actual %v", engine.moves, m.Moves)
+	}
+	if m.Winner != m.Black || m.WinnerEngine != m.BlackEngine {
+		t.Errorf("Run: expected winner %s, actual %s", m.Black, m.Winner)
+	}
+}
+
+func TestRunDrawRules(t *testing.T) {
+	shuffle := []string{"c2d2", "f7e7", "d2c2", "e7f7"}
+	var moves []string
+	for len(moves) < MaxMoves {
+		moves = append(moves, shuffle...)
+	}
+	var tests = []struct {
+		fen    string
+		plies  int
+		reason string
+	}{
+		// the start position occurs for the third time after two rounds of the shuffle
+		{"7k/5q2/8/8/8/8/2Q5/K7 w - - 0 1", 8, DrawThreefold},
+		{"7k/5q2/8/8/8/8/2Q5/K7 w - - 94 60", 6, DrawFiftyMove},
+	}
+	for _, tt := range tests {
+		engine := &mockEngine{moves: moves}
+		m, err := NewMatchFromFEN("e1", engine, "e2", engine, tt.fen)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		winner, err := m.Run()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if winner != "" {
+			t.Errorf("Run() from %s: expected a draw, actual winner %s", tt.fen, winner)
+		}
+		if len(m.Moves) != tt.plies || m.DrawReason != tt.reason {
+			t.Errorf("Run() from %s: expected a %s draw after %d plies, actual %s after %d", tt.fen, tt.reason, tt.plies, m.DrawReason, len(m.Moves))
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package gostockfish
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PGNTagRegex describes the regular expression for PGN tag pairs, i.e. [Event "Casual game"]
+var PGNTagRegex = regexp.MustCompile(`\[(?P<name>\w+)\s+"(?P<value>(?:[^"\\]|\\.)*)"\]`)
+
+// moveNumberRegex matches move numbers in PGN movetext, i.e. '12.' or '12...'
+var moveNumberRegex = regexp.MustCompile(`^\d+\.+`)
+
+// ParsePGN parses the main line of a PGN game. Tag pairs, comments, variations and NAGs are
+// ignored, except for the FEN tag which sets the starting position. Returns the starting
+// position in FEN notation and the moves in full algebraic notation.
+func ParsePGN(pgn string) (string, []string, error) {
+	fen := StartFEN
+	for _, tag := range PGNTagRegex.FindAllStringSubmatch(pgn, -1) {
+		if tag[1] == "FEN" {
+			fen = tag[2]
+		}
+	}
+	movetext := PGNTagRegex.ReplaceAllString(pgn, " ")
+
+	board, err := ParseFEN(fen)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var moves []string
+	for _, san := range pgnMovetext(movetext) {
+		number := fmt.Sprintf("%d.", board.FullmoveNumber)
+		if !board.WhiteToMove {
+			number = fmt.Sprintf("%d...", board.FullmoveNumber)
+		}
+		m, err := board.parseSAN(san)
+		if err != nil {
+			return "", nil, fmt.Errorf("Could not parse move %s %s: %s", number, san, err.Error())
+		}
+		moves = append(moves, m.UCI())
+		board.play(m)
+	}
+
+	return fen, moves, nil
+}
+
+// pgnMovetext splits PGN movetext into SAN moves, dropping move numbers, comments,
+// variations, NAGs and the game result
+func pgnMovetext(movetext string) []string {
+	var plain strings.Builder
+	variation := 0
+	comment := false
+	lineComment := false
+
+	for _, c := range movetext {
+		switch {
+		case lineComment:
+			if c == '\n' {
+				lineComment = false
+				plain.WriteRune(' ')
+			}
+		case comment:
+			if c == '}' {
+				comment = false
+				plain.WriteRune(' ')
+			}
+		case c == '{':
+			comment = true
+		case c == ';':
+			lineComment = true
+		case c == '(':
+			variation++
+		case c == ')':
+			if variation > 0 {
+				variation--
+			}
+			plain.WriteRune(' ')
+		case variation > 0:
+		default:
+			plain.WriteRune(c)
+		}
+	}
+
+	var sans []string
+	for _, token := range strings.Fields(plain.String()) {
+		token = moveNumberRegex.ReplaceAllString(token, "")
+		if token == "" || strings.HasPrefix(token, "$") {
+			continue
+		}
+		if token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*" {
+			continue
+		}
+		sans = append(sans, token)
+	}
+	return sans
+}
+
+// PGNLineLength is the maximum length of movetext lines written by Match.PGN
+const PGNLineLength = 80
+
+// PGN exports the match in Portable Game Notation: the Seven Tag Roster (Event, Site and Round
+// are unknown, Date is today) with the engine names and the result, followed by the moves in
+// standard algebraic notation. Games from another than the standard starting position get SetUp
+// and FEN tags, games classified by ECOTable an ECO tag. The result is "1-0" or "0-1" if
+// match.Winner is set, "1/2-1/2" otherwise.
+func (match *Match) PGN() (string, error) {
+	board, err := match.startBoard()
+	if err != nil {
+		return "", err
+	}
+	moves, err := match.MovesSAN()
+	if err != nil {
+		return "", err
+	}
+
+	result := "1/2-1/2"
+	if match.Winner != "" && match.Winner == match.White {
+		result = "1-0"
+	} else if match.Winner != "" && match.Winner == match.Black {
+		result = "0-1"
+	}
+
+	var pgn strings.Builder
+	tag := func(name string, value string) {
+		value = strings.Replace(value, "\\", "\\\\", -1)
+		value = strings.Replace(value, "\"", "\\\"", -1)
+		fmt.Fprintf(&pgn, "[%s \"%s\"]\n", name, value)
+	}
+	tag("Event", "?")
+	tag("Site", "?")
+	tag("Date", time.Now().Format("2006.01.02"))
+	tag("Round", "?")
+	tag("White", match.White)
+	tag("Black", match.Black)
+	tag("Result", result)
+	if match.StartFEN != "" && match.StartFEN != StartFEN {
+		tag("SetUp", "1")
+		tag("FEN", match.StartFEN)
+	}
+	if code, _ := match.ECO(); code != "" {
+		tag("ECO", code)
+	}
+	pgn.WriteString("\n")
+
+	var tokens []string
+	number, white := board.FullmoveNumber, board.WhiteToMove
+	for i, san := range moves {
+		if white {
+			tokens = append(tokens, fmt.Sprintf("%d.", number))
+		} else if i == 0 {
+			tokens = append(tokens, fmt.Sprintf("%d...", number))
+		}
+		tokens = append(tokens, san)
+		if !white {
+			number++
+		}
+		white = !white
+	}
+	tokens = append(tokens, result)
+
+	length := 0
+	for _, token := range tokens {
+		if length > 0 && length+1+len(token) > PGNLineLength {
+			pgn.WriteString("\n")
+			length = 0
+		} else if length > 0 {
+			pgn.WriteString(" ")
+			length++
+		}
+		pgn.WriteString(token)
+		length += len(token)
+	}
+	pgn.WriteString("\n")
+
+	return pgn.String(), nil
+}
@@ -0,0 +1,120 @@
+package gostockfish
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParsePGN(t *testing.T) {
+	pgn := `[Event "Casual game"]
+[White "e1"]
+[Black "e2"]
+[Result "1-0"]
+
+1. e4 e5 2. Bc4 {the Italian bishop} Nc6 3. Qh5?! (3. Nf3 Nf6) Nf6?? $4
+4. Qxf7# ; mate
+1-0`
+
+	fen, moves, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if fen != StartFEN {
+		t.Errorf("ParsePGN: expected start position, actual %s", fen)
+	}
+	expected := []string{"e2e4", "e7e5", "f1c4", "b8c6", "d1h5", "g8f6", "h5f7"}
+	if !reflect.DeepEqual(moves, expected) {
+		t.Errorf("ParsePGN: expected %v, actual %v", expected, moves)
+	}
+}
+
+func TestParsePGNFromFEN(t *testing.T) {
+	pgn := `[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1"]
+
+1... Kd7 2. e4 *`
+
+	fen, moves, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if fen != "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1" {
+		t.Errorf("ParsePGN: expected FEN tag as start position, actual %s", fen)
+	}
+	expected := []string{"e8d7", "e2e4"}
+	if !reflect.DeepEqual(moves, expected) {
+		t.Errorf("ParsePGN: expected %v, actual %v", expected, moves)
+	}
+}
+
+func TestParsePGNIllegalMove(t *testing.T) {
+	_, _, err := ParsePGN("1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Bb5")
+	if err == nil {
+		t.Fatalf("ParsePGN: expected error for illegal move")
+	}
+	if !strings.Contains(err.Error(), "4. Bb5") {
+		t.Errorf("ParsePGN: expected error to name move \"4. Bb5\", actual %s", err)
+	}
+}
+
+func TestMatchPGN(t *testing.T) {
+	m := &Match{
+		White:  "stockfish \"12\"",
+		Black:  "e2",
+		Winner: "stockfish \"12\"",
+		Moves:  []string{"e2e4", "e7e5", "d1h5", "b8c6", "f1c4", "g8f6", "h5f7"},
+	}
+	pgn, err := m.PGN()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	expected := regexp.MustCompile(`^\[Event "\?"\]
+\[Site "\?"\]
+\[Date "\d{4}\.\d{2}\.\d{2}"\]
+\[Round "\?"\]
+\[White "stockfish \\"12\\""\]
+\[Black "e2"\]
+\[Result "1-0"\]
+\[ECO "C20"\]
+
+1\. e4 e5 2\. Qh5 Nc6 3\. Bc4 Nf6 4\. Qxf7# 1-0
+$`)
+	if !expected.MatchString(pgn) {
+		t.Errorf("PGN(): unexpected\n%s", pgn)
+	}
+	_, moves, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !reflect.DeepEqual(moves, m.Moves) {
+		t.Errorf("ParsePGN(PGN()): expected %v, actual %v", m.Moves, moves)
+	}
+
+	m = &Match{White: "e1", Black: "e2", StartFEN: "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", Moves: []string{"e8d7", "e2e4"}}
+	pgn, err = m.PGN()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !strings.Contains(pgn, "[Result \"1/2-1/2\"]\n[SetUp \"1\"]\n[FEN \"4k3/8/8/8/8/8/4P3/4K3 b - - 0 1\"]\n\n1... Kd7 2. e4 1/2-1/2\n") {
+		t.Errorf("PGN() from FEN: unexpected\n%s", pgn)
+	}
+
+	var long []string
+	for i := 0; i < 20; i++ {
+		long = append(long, "g1f3", "g8f6", "f3g1", "f6g8")
+	}
+	m = &Match{White: "e1", Black: "e2", Winner: "e2", Moves: long}
+	pgn, err = m.PGN()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, line := range strings.Split(pgn, "\n") {
+		if len(line) > PGNLineLength {
+			t.Errorf("PGN(): expected lines of at most %d characters, actual %d", PGNLineLength, len(line))
+		}
+	}
+	if !strings.HasSuffix(pgn, " 0-1\n") {
+		t.Errorf("PGN(): expected result 0-1, actual\n%s", pgn)
+	}
+}
@@ -0,0 +1,153 @@
+package gostockfish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PositionReport is a snapshot of the search of a position. Lines holds the final info line
+// of each principal variation, best first.
+type PositionReport struct {
+	FEN      string
+	BestMove string
+	Ponder   string
+	Lines    []*Info
+	Depth    int
+	Seldepth int
+	Nodes    int64
+	Nps      int64
+	Time     time.Duration
+}
+
+// AnalyzeReport searches the position in FEN notation to the given depth with multipv
+// principal variations and assembles the results. The option 'MultiPV' is restored afterwards,
+// the engine is left in the analyzed position.
+func (engine *Engine) AnalyzeReport(fen string, depth int, multipv int) (*PositionReport, error) {
+	if depth < 1 {
+		return nil, fmt.Errorf("Invalid depth %d", depth)
+	}
+	if multipv < 1 {
+		return nil, fmt.Errorf("Invalid number of principal variations %d", multipv)
+	}
+	_, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	if multipv > 1 && len(engine.options) > 0 && !engine.HasOption("MultiPV") {
+		return nil, errors.New("Engine does not support option MultiPV")
+	}
+
+	previous := engine.multiPV()
+	err = engine.SetOption("MultiPV", strconv.Itoa(multipv))
+	if err != nil {
+		return nil, err
+	}
+	defer engine.SetOption("MultiPV", previous)
+
+	err = engine.SetFENPosition(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := map[int]*Info{}
+	bestMove, err := engine.search(context.Background(), fmt.Sprintf("go depth %d", depth), func(info *Info) bool {
+		if info.Depth > 0 && !info.IsCurrMove() {
+			lines[info.Multipv] = info
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PositionReport{
+		FEN:      fen,
+		BestMove: bestMove.Move,
+		Ponder:   bestMove.Ponder,
+	}
+	for _, info := range lines {
+		report.Lines = append(report.Lines, info)
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		return report.Lines[i].Multipv < report.Lines[j].Multipv
+	})
+	if bestMove.Info != nil {
+		stats := bestMove.Stats()
+		report.Depth = stats.Depth
+		report.Seldepth = stats.Seldepth
+		report.Nodes = stats.Nodes
+		report.Nps = stats.Nps
+		report.Time = stats.Elapsed
+	}
+
+	return report, nil
+}
+
+// multiPV returns the current value of the option 'MultiPV'
+func (engine *Engine) multiPV() string {
+	if value, ok := engine.Param["MultiPV"]; ok {
+		return value
+	}
+	if option, ok := engine.option("MultiPV"); ok && option.Default != "" {
+		return option.Default
+	}
+	return "1"
+}
+
+// Candidate is one of the best moves of a position, see AnalyzeCandidates
+type Candidate struct {
+	Move  string
+	Score Score
+	Info  *Info
+	// Gap is the number of centipawns the candidate is behind the best candidate, 0 for the best
+	Gap int
+}
+
+// AnalyzeCandidates searches the position in FEN notation to the given depth and returns the
+// n best moves with their scores, best first, see AnalyzeReport. A large Gap of the second
+// candidate marks an only move. Scores are from the point of view of the side to move, mates
+// rank above all centipawn scores and the faster mate ranks higher.
+func (engine *Engine) AnalyzeCandidates(fen string, depth int, n int) ([]*Candidate, error) {
+	report, err := engine.AnalyzeReport(fen, depth, n)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*Candidate
+	for _, info := range report.Lines {
+		moves := strings.Fields(info.Pv)
+		if len(moves) == 0 {
+			continue
+		}
+		candidates = append(candidates, &Candidate{
+			Move:  moves[0],
+			Score: info.Score,
+			Info:  info,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidateCentipawns(candidates[i].Score) > candidateCentipawns(candidates[j].Score)
+	})
+	for _, candidate := range candidates {
+		candidate.Gap = candidateCentipawns(candidates[0].Score) - candidateCentipawns(candidate.Score)
+	}
+
+	return candidates, nil
+}
+
+// candidateCentipawns returns the score in centipawns to rank candidates, counting mates as
+// mateCentipawns less the number of moves to mate
+func candidateCentipawns(score Score) int {
+	if score.Eval != "mate" {
+		return score.Value
+	}
+	if score.Value > 0 {
+		return mateCentipawns - score.Value
+	}
+	return -mateCentipawns - score.Value
+}
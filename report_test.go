@@ -0,0 +1,98 @@
+package gostockfish
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeReport(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go depth 2" {
+			return []string{
+				"info depth 1 seldepth 1 multipv 1 score cp 50 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"info depth 1 seldepth 1 multipv 2 score cp 40 nodes 20 nps 20000 tbhits 0 time 1 pv d2d4",
+				"info depth 2 seldepth 2 multipv 1 score cp 35 nodes 80 nps 40000 tbhits 0 time 2 pv d2d4 d7d5",
+				"info depth 2 seldepth 3 multipv 2 score cp 30 nodes 100 nps 50000 tbhits 0 time 2 pv e2e4 e7e5",
+				"bestmove d2d4 ponder d7d5",
+			}
+		}
+		return nil
+	})
+	engine.Param["MultiPV"] = "1"
+
+	report, err := engine.AnalyzeReport(StartFEN, 2, 2)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if report.BestMove != "d2d4" || report.Ponder != "d7d5" {
+		t.Errorf("AnalyzeReport: expected d2d4 ponder d7d5, actual %s ponder %s", report.BestMove, report.Ponder)
+	}
+	var pvs []string
+	for _, line := range report.Lines {
+		pvs = append(pvs, line.Pv)
+	}
+	if expected := []string{"d2d4 d7d5", "e2e4 e7e5"}; !reflect.DeepEqual(pvs, expected) {
+		t.Errorf("AnalyzeReport: expected lines %v, actual %v", expected, pvs)
+	}
+	if report.Depth != 2 || report.Seldepth != 3 || report.Nodes != 100 || report.Time != 2*time.Millisecond {
+		t.Errorf("AnalyzeReport: unexpected statistics %+v", report)
+	}
+	expected := []string{
+		"setoption name MultiPV value 2",
+		"isready",
+		"position fen " + StartFEN,
+		"isready",
+		"go depth 2",
+		"setoption name MultiPV value 1",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("AnalyzeReport: expected %v, actual %v", expected, actual)
+	}
+
+	for _, args := range []struct {
+		fen     string
+		depth   int
+		multipv int
+	}{{StartFEN, 0, 1}, {StartFEN, 2, 0}, {"8/8/8/8/8/8/8/8 w - - 0 1", 2, 1}} {
+		if _, err := engine.AnalyzeReport(args.fen, args.depth, args.multipv); err == nil {
+			t.Errorf("AnalyzeReport(\"%s\", %d, %d): expected error", args.fen, args.depth, args.multipv)
+		}
+	}
+}
+
+func TestAnalyzeCandidates(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go depth 8" {
+			return []string{
+				"info depth 8 seldepth 10 multipv 1 score mate 2 nodes 900 nps 90000 tbhits 0 time 10 pv d1h5 g7g6 h5e5",
+				"info depth 8 seldepth 9 multipv 2 score cp 120 nodes 900 nps 90000 tbhits 0 time 10 pv f1c4 g8f6",
+				"info depth 8 seldepth 9 multipv 3 score mate 4 nodes 900 nps 90000 tbhits 0 time 10 pv d1f3 b8c6",
+				"bestmove d1h5 ponder g7g6",
+			}
+		}
+		return nil
+	})
+
+	fen := "rnbqkbnr/ppppp2p/5p2/6p1/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3"
+	candidates, err := engine.AnalyzeCandidates(fen, 8, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	var moves []string
+	var gaps []int
+	for _, candidate := range candidates {
+		moves = append(moves, candidate.Move)
+		gaps = append(gaps, candidate.Gap)
+	}
+	if expected := []string{"d1h5", "d1f3", "f1c4"}; !reflect.DeepEqual(moves, expected) {
+		t.Errorf("AnalyzeCandidates: expected moves %v, actual %v", expected, moves)
+	}
+	if expected := []int{0, 2, mateCentipawns - 2 - 120}; !reflect.DeepEqual(gaps, expected) {
+		t.Errorf("AnalyzeCandidates: expected gaps %v, actual %v", expected, gaps)
+	}
+	if sent := fake.sent(); sent[len(sent)-2] != "setoption name MultiPV value 1" {
+		t.Errorf("AnalyzeCandidates: expected MultiPV to be restored, actual %v", sent)
+	}
+}
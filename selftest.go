@@ -0,0 +1,61 @@
+package gostockfish
+
+import (
+	"context"
+	"time"
+)
+
+// SelfTestDepth is the search depth of the games played by SelfTest
+var SelfTestDepth = 1
+
+// SelfTestMaxMoves is the number of plies after which SelfTest stops the game
+var SelfTestMaxMoves = 60
+
+// SelfTestReport describes the game played by SelfTest. Winner is "engine" or "clone" for a
+// decisive result and empty for a draw or an unfinished game.
+type SelfTestReport struct {
+	Moves    []string
+	Winner   string
+	Finished bool
+	Duration time.Duration
+}
+
+// SelfTest is a quick smoke test of the engine configuration: it plays a short game of the
+// engine against a clone of itself at SelfTestDepth, which exercises the options, the search,
+// the output parsing and the game termination. The game is stopped after SelfTestMaxMoves
+// plies, which is not an error. The current position of the engine is lost.
+func (engine *Engine) SelfTest() (*SelfTestReport, error) {
+	started := time.Now()
+
+	clone, err := engine.Clone()
+	if err != nil {
+		return nil, err
+	}
+	defer clone.Close()
+
+	depth := engine.Depth
+	engine.Depth, clone.Depth = SelfTestDepth, SelfTestDepth
+	defer func() { engine.Depth = depth }()
+
+	match, err := NewMatch("engine", engine, "clone", clone)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SelfTestReport{}
+	for len(match.Moves) < SelfTestMaxMoves {
+		moved, err := match.MoveContext(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		if !moved {
+			report.Finished = true
+			break
+		}
+	}
+
+	report.Moves = match.Moves
+	report.Winner = match.Winner
+	report.Duration = time.Since(started)
+	return report, nil
+}
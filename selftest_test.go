@@ -0,0 +1,31 @@
+package gostockfish
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	// answers the first search with a move and the second one with stalemate
+	script := strings.Replace(fakeExecutable, "\t\tquit) exit 0;;\n", `		position*moves\ e2e4) stalemate=1;;
+		position*) stalemate=;;
+		go*) if [ -n "$stalemate" ]; then echo "info depth 0 score cp 0"; echo "bestmove (none)"; else echo "bestmove e2e4"; fi;;
+		quit) exit 0;;
+`, 1)
+	engine, err := NewEngineRaw(writeFakeExecutable(t, script), 8, nil)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer engine.Close()
+
+	report, err := engine.SelfTest()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !report.Finished || report.Winner != "" || len(report.Moves) != 1 {
+		t.Errorf("SelfTest: expected a drawn game after 1 move, actual %+v", report)
+	}
+	if engine.Depth != 8 {
+		t.Errorf("SelfTest: expected depth 8 to be restored, actual %d", engine.Depth)
+	}
+}
@@ -0,0 +1,138 @@
+package gostockfish
+
+import (
+	"context"
+)
+
+// Opening is the starting point of a game in a series: the moves played from the position in
+// FEN notation, or from the standard starting position if FEN is empty
+type Opening struct {
+	Name  string
+	FEN   string
+	Moves []string
+}
+
+// Series is a match of several games between two engines. Every opening of the pool is played
+// twice with colors reversed, which reduces the variance of the result and avoids playing the
+// same game over and over.
+type Series struct {
+	E1       string
+	Engine1  UCIEngine
+	E2       string
+	Engine2  UCIEngine
+	Openings []*Opening
+	// Setup, if not nil, is called before each game, i.e. to configure adjudication
+	Setup func(match *Match)
+}
+
+// SeriesGame is the result of a single game of a series
+type SeriesGame struct {
+	Opening *Opening
+	White   string
+	Black   string
+	Winner  string
+	Moves   []string
+}
+
+// SeriesResult is the result of a series. Wins counts the games won per engine name.
+type SeriesResult struct {
+	Games []*SeriesGame
+	Wins  map[string]int
+	Draws int
+}
+
+// NewSeries setups a series between two engines with the given opening pool
+func NewSeries(e1 string, engine1 UCIEngine, e2 string, engine2 UCIEngine, openings []*Opening) *Series {
+	return &Series{
+		E1:       e1,
+		Engine1:  engine1,
+		E2:       e2,
+		Engine2:  engine2,
+		Openings: openings,
+	}
+}
+
+// Run plays 2*len(series.Openings) games: each opening first with Engine1 as white, then with
+// Engine2 as white. Stops at the first error or once ctx is done.
+func (series *Series) Run(ctx context.Context) (*SeriesResult, error) {
+	result := &SeriesResult{
+		Wins: map[string]int{series.E1: 0, series.E2: 0},
+	}
+
+	for _, opening := range series.Openings {
+		pairs := [][2]int{{1, 2}, {2, 1}}
+		for _, pair := range pairs {
+			white, whiteEngine := series.player(pair[0])
+			black, blackEngine := series.player(pair[1])
+			match, err := newOpeningMatch(white, whiteEngine, black, blackEngine, opening)
+			if err != nil {
+				return nil, err
+			}
+			if series.Setup != nil {
+				series.Setup(match)
+			}
+
+			winner, err := match.RunContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			result.Games = append(result.Games, &SeriesGame{
+				Opening: opening,
+				White:   white,
+				Black:   black,
+				Winner:  winner,
+				Moves:   match.Moves,
+			})
+			if winner == "" {
+				result.Draws++
+			} else {
+				result.Wins[winner]++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Score returns the points of an engine in the series, one per win and a half per draw
+func (result *SeriesResult) Score(name string) float64 {
+	return float64(result.Wins[name]) + float64(result.Draws)/2
+}
+
+// player returns the name and engine of the first or second engine of the series
+func (series *Series) player(n int) (string, UCIEngine) {
+	if n == 1 {
+		return series.E1, series.Engine1
+	}
+	return series.E2, series.Engine2
+}
+
+// newOpeningMatch setups a match with fixed colors starting from the opening
+func newOpeningMatch(white string, whiteEngine UCIEngine, black string, blackEngine UCIEngine, opening *Opening) (*Match, error) {
+	if opening.FEN != "" {
+		_, err := ParseFEN(opening.FEN)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := NewMatch(white, whiteEngine, black, blackEngine)
+	if err != nil {
+		return nil, err
+	}
+	m.White, m.WhiteEngine = white, whiteEngine
+	m.Black, m.BlackEngine = black, blackEngine
+	m.StartFEN = opening.FEN
+	m.Moves = append([]string{}, opening.Moves...)
+
+	err = m.setPosition(whiteEngine)
+	if err != nil {
+		return nil, err
+	}
+	err = m.setPosition(blackEngine)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
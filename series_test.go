@@ -0,0 +1,50 @@
+package gostockfish
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSeries(t *testing.T) {
+	replies := map[int][]string{
+		0: {"info depth 0 score cp 0", "bestmove (none)"},
+		1: {"info depth 0 score mate 0", "bestmove (none)"},
+	}
+	openings := []*Opening{
+		{Name: "King's pawn", Moves: []string{"e2e4"}},
+		{Name: "Bare kings", FEN: "4k3/8/8/8/8/8/8/4K3 w - - 0 1"},
+	}
+	series := NewSeries("e1", newPlyEngine(replies), "e2", newPlyEngine(replies), openings)
+	setups := 0
+	series.Setup = func(match *Match) {
+		setups++
+	}
+
+	result, err := series.Run(context.Background())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(result.Games) != 4 || setups != 4 {
+		t.Fatalf("Run: expected 4 games, actual %d (%d setups)", len(result.Games), setups)
+	}
+	expected := []struct {
+		white  string
+		winner string
+	}{
+		{"e1", "e1"},
+		{"e2", "e2"},
+		{"e1", ""},
+		{"e2", ""},
+	}
+	for i, game := range result.Games {
+		if game.White != expected[i].white || game.Winner != expected[i].winner {
+			t.Errorf("Run: expected game %d with white %s won by \"%s\", actual white %s won by \"%s\"", i, expected[i].white, expected[i].winner, game.White, game.Winner)
+		}
+	}
+	if result.Wins["e1"] != 1 || result.Wins["e2"] != 1 || result.Draws != 2 {
+		t.Errorf("Run: expected 1 win each and 2 draws, actual %v and %d draws", result.Wins, result.Draws)
+	}
+	if score := result.Score("e1"); score != 2 {
+		t.Errorf("Score(\"e1\"): expected 2, actual %f", score)
+	}
+}
@@ -0,0 +1,167 @@
+package gostockfish
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ServeOptions let Serve log the relayed traffic and override options set by the clients
+type ServeOptions struct {
+	// OnLine, if not nil, is called for every line relayed, with dir ">" for the commands of
+	// the client and the overrides sent to the engine, and "<" for the engine output sent to
+	// the client. The calls do not overlap.
+	OnLine func(dir, line string)
+	// Overrides are options set on the engine whatever the client sends: they are sent when
+	// the client connects, after every 'ucinewgame' and after every 'setoption' of the client
+	// for one of them.
+	Overrides map[string]string
+}
+
+// Serve accepts UCI clients (i.e. chess GUIs) on the listener and relays their traffic to the
+// engine, one client at a time, so a configured engine can be used remotely. Returns the
+// error of the listener, i.e. once it is closed.
+func (engine *Engine) Serve(listener net.Listener) error {
+	return engine.ServeWithOptions(listener, ServeOptions{})
+}
+
+// ServeWithOptions is like Serve and applies options to every client, see ServeOptions
+func (engine *Engine) ServeWithOptions(listener net.Listener, options ServeOptions) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		engine.ServeConnWithOptions(conn, options)
+		conn.Close()
+	}
+}
+
+// ServeConn relays UCI traffic between a single client and the engine until the client
+// disconnects or sends 'quit'. The engine process is kept running: 'quit' is not passed on,
+// any running search is stopped and the engine is synchronized with 'isready' before
+// ServeConn returns, so the engine can serve the next client or be used directly again.
+func (engine *Engine) ServeConn(conn io.ReadWriter) error {
+	return engine.ServeConnWithOptions(conn, ServeOptions{})
+}
+
+// ServeConnWithOptions is like ServeConn and applies options to the client, see ServeOptions
+func (engine *Engine) ServeConnWithOptions(conn io.ReadWriter, options ServeOptions) error {
+	var logMu sync.Mutex
+	logLine := func(dir, line string) {
+		if options.OnLine == nil {
+			return
+		}
+		logMu.Lock()
+		defer logMu.Unlock()
+		options.OnLine(dir, line)
+	}
+	send := func(command string) {
+		logLine(">", command)
+		engine.Put(command)
+	}
+	override := func(name string) {
+		send(fmt.Sprintf("setoption name %s value %s", name, options.Overrides[name]))
+	}
+	overrideAll := func() {
+		var names []string
+		for name := range options.Overrides {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			override(name)
+		}
+	}
+
+	var mu sync.Mutex
+	pending := 0
+	closing := false
+	relayed := make(chan struct{})
+
+	// engine to client
+	go func() {
+		defer close(relayed)
+		for {
+			line, err := engine.readLine(context.Background())
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			if line == "readyok" {
+				pending--
+			}
+			forward := !closing
+			done := closing && pending <= 0
+			mu.Unlock()
+			if forward {
+				// once closing, the client is gone and there is nobody left to read
+				logLine("<", line)
+				io.WriteString(conn, line+"\n")
+			}
+			if done {
+				return
+			}
+		}
+	}()
+
+	overrideAll()
+
+	// client to engine
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+		if command == "quit" {
+			break
+		}
+		if command == "isready" {
+			mu.Lock()
+			pending++
+			mu.Unlock()
+		}
+		send(command)
+		if command == "ucinewgame" {
+			overrideAll()
+		} else if name, ok := overriddenOption(options.Overrides, command); ok {
+			override(name)
+		}
+	}
+
+	mu.Lock()
+	pending++
+	closing = true
+	mu.Unlock()
+	engine.Put("stop")
+	engine.Put("isready")
+	<-relayed
+
+	return scanner.Err()
+}
+
+// overriddenOption returns the name in overrides of the option set by a 'setoption' command,
+// matching the name case-insensitively like Stockfish does
+func overriddenOption(overrides map[string]string, command string) (string, bool) {
+	fields := strings.Fields(command)
+	if len(fields) < 3 || fields[0] != "setoption" || fields[1] != "name" {
+		return "", false
+	}
+	var words []string
+	for _, field := range fields[2:] {
+		if field == "value" {
+			break
+		}
+		words = append(words, field)
+	}
+	option := strings.Join(words, " ")
+	for name := range overrides {
+		if strings.EqualFold(name, option) {
+			return name, true
+		}
+	}
+	return "", false
+}
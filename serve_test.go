@@ -0,0 +1,119 @@
+package gostockfish
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestServeConn(t *testing.T) {
+	engine, fake := newFakeEngine(func(command string) []string {
+		if command == "go depth 1" {
+			return []string{
+				"info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4",
+				"bestmove e2e4",
+			}
+		}
+		return nil
+	})
+	server, client := net.Pipe()
+
+	served := make(chan error)
+	go func() {
+		served <- engine.ServeConn(server)
+	}()
+
+	reader := bufio.NewReader(client)
+	expect := func(expected string) {
+		line, _, err := reader.ReadLine()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if string(line) != expected {
+			t.Errorf("ServeConn: expected %s, actual %s", expected, line)
+		}
+	}
+
+	io.WriteString(client, "isready\n")
+	expect("readyok")
+	io.WriteString(client, "go depth 1\n")
+	expect("info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 20000 tbhits 0 time 1 pv e2e4")
+	expect("bestmove e2e4")
+	io.WriteString(client, "quit\n")
+
+	err := <-served
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []string{"isready", "go depth 1", "stop", "isready"}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ServeConn: expected engine to receive %v, actual %v", expected, actual)
+	}
+	err = engine.IsReady()
+	if err != nil {
+		t.Errorf("IsReady() after ServeConn: %s", err)
+	}
+}
+
+func TestServeConnWithOptions(t *testing.T) {
+	engine, fake := newFakeEngine(nil)
+	server, client := net.Pipe()
+
+	var traffic []string
+	options := ServeOptions{
+		OnLine: func(dir, line string) {
+			traffic = append(traffic, dir+" "+line)
+		},
+		Overrides: map[string]string{"Hash": "64"},
+	}
+	served := make(chan error)
+	go func() {
+		served <- engine.ServeConnWithOptions(server, options)
+	}()
+
+	reader := bufio.NewReader(client)
+	io.WriteString(client, "setoption name hash value 16\n")
+	io.WriteString(client, "setoption name Threads value 2\n")
+	io.WriteString(client, "ucinewgame\n")
+	io.WriteString(client, "isready\n")
+	line, _, err := reader.ReadLine()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if string(line) != "readyok" {
+		t.Errorf("ServeConnWithOptions: expected readyok, actual %s", line)
+	}
+	io.WriteString(client, "quit\n")
+
+	err = <-served
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := []string{
+		"setoption name Hash value 64",
+		"setoption name hash value 16",
+		"setoption name Hash value 64",
+		"setoption name Threads value 2",
+		"ucinewgame",
+		"setoption name Hash value 64",
+		"isready",
+		"stop",
+		"isready",
+	}
+	if actual := fake.sent(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ServeConnWithOptions: expected engine to receive %v, actual %v", expected, actual)
+	}
+
+	var expectedTraffic []string
+	for _, command := range expected[:len(expected)-2] {
+		expectedTraffic = append(expectedTraffic, "> "+command)
+	}
+	expectedTraffic = append(expectedTraffic, "< readyok")
+	if !reflect.DeepEqual(traffic, expectedTraffic) {
+		t.Errorf("ServeConnWithOptions: expected OnLine to be called with %v, actual %v", expectedTraffic, traffic)
+	}
+}
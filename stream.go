@@ -0,0 +1,50 @@
+package gostockfish
+
+import (
+	"context"
+)
+
+// AnalyzeStreamBuffer is the number of info lines AnalyzeStream buffers for a slow consumer
+var AnalyzeStreamBuffer = 16
+
+// AnalysisResult is the outcome of a search streamed by AnalyzeStream
+type AnalysisResult struct {
+	BestMove *BestMove
+	Err      error
+}
+
+// AnalyzeStream searches the current position within the limits (see BestMoveLimits) in the
+// background and delivers the info lines of the search on the first channel, which is closed
+// once the search ends. The result is delivered on the second channel afterwards. The search
+// is stopped once ctx is done.
+//
+// The delivery is lossy: the info channel buffers AnalyzeStreamBuffer lines and, once it is
+// full, the oldest line is dropped to make room for the latest, so a slow consumer never
+// stalls reading the engine output (which would eventually block the engine writing it). For
+// a live display only the latest lines matter. The engine must not be used otherwise until
+// the result has been received.
+func (engine *Engine) AnalyzeStream(ctx context.Context, limits SearchLimits) (<-chan *Info, <-chan AnalysisResult) {
+	infos := make(chan *Info, AnalyzeStreamBuffer)
+	result := make(chan AnalysisResult, 1)
+
+	go func() {
+		bestMove, err := engine.search(ctx, limits.command(engine.Depth), func(info *Info) bool {
+			for {
+				select {
+				case infos <- info:
+					return false
+				default:
+				}
+				// full: drop the oldest line, unless the consumer took it meanwhile
+				select {
+				case <-infos:
+				default:
+				}
+			}
+		})
+		close(infos)
+		result <- AnalysisResult{BestMove: bestMove, Err: err}
+	}()
+
+	return infos, result
+}
@@ -0,0 +1,44 @@
+package gostockfish
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestAnalyzeStreamSlowConsumer(t *testing.T) {
+	engine, _ := newFakeEngine(func(command string) []string {
+		if command != "go depth 2" {
+			return nil
+		}
+		var lines []string
+		for depth := 1; depth <= 100; depth++ {
+			lines = append(lines, fmt.Sprintf("info depth %d seldepth %d multipv 1 score cp 20 nodes %d nps 100000 tbhits 0 time %d pv e2e4", depth, depth, depth*100, depth))
+		}
+		return append(lines, "bestmove e2e4")
+	})
+
+	infos, results := engine.AnalyzeStream(context.Background(), SearchLimits{})
+	// the slowest consumer: nothing is read before the search ends
+	result := <-results
+	if result.Err != nil {
+		t.Fatalf(result.Err.Error())
+	}
+	if result.BestMove.Move != "e2e4" {
+		t.Errorf("AnalyzeStream: expected bestmove e2e4, actual %s", result.BestMove.Move)
+	}
+
+	var depths []int
+	for info := range infos {
+		depths = append(depths, info.Depth)
+	}
+	if len(depths) != AnalyzeStreamBuffer {
+		t.Fatalf("AnalyzeStream: expected %d buffered infos, actual %v", AnalyzeStreamBuffer, depths)
+	}
+	for i, depth := range depths {
+		if expected := 100 - AnalyzeStreamBuffer + 1 + i; depth != expected {
+			t.Errorf("AnalyzeStream: expected the latest infos, actual depths %v", depths)
+			break
+		}
+	}
+}